@@ -0,0 +1,220 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/config"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// Admin serves operational endpoints: profiling, the log level,
+// runtime and cache statistics, and the list of routes that each
+// listener serves. These endpoints aren't meant for tenants, so
+// they're mounted only on listeners that are configured with the
+// "admin" route group.
+type Admin struct {
+	level   zap.AtomicLevel
+	cache   client.Reader
+	started time.Time
+
+	mu      sync.RWMutex
+	routers []namedRouter
+}
+
+type namedRouter struct {
+	listener string
+	router   *mux.Router
+}
+
+// routeInfo describes one route on the wire.
+type routeInfo struct {
+	Listener string   `json:"listener"`
+	Name     string   `json:"name,omitempty"`
+	Path     string   `json:"path"`
+	Methods  []string `json:"methods,omitempty"`
+}
+
+// logLevel is the body of the /loglevel endpoint.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// New configures a new Admin. level is the logger's level, which
+// the /loglevel endpoint changes. cache is the manager's cache, which
+// the /stats endpoint reports on.
+func New(level zap.AtomicLevel, cache client.Reader) *Admin {
+	return &Admin{
+		level:   level,
+		cache:   cache,
+		started: time.Now(),
+	}
+}
+
+// AddRouter registers a listener's router so its routes show up in
+// the /routes endpoint.
+func (a *Admin) AddRouter(listener string, router *mux.Router) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.routers = append(a.routers, namedRouter{listener: listener, router: router})
+}
+
+// SetupRoutes sets up the provided mux.Router to handle the admin
+// routes. They're mounted at the root of the router, not under the
+// tenant-facing URL root.
+func (a *Admin) SetupRoutes(router *mux.Router) {
+	router.HandleFunc("/loglevel", a.getLogLevel).Methods(http.MethodGet).Name("loglevel")
+	router.HandleFunc("/loglevel", a.putLogLevel).Methods(http.MethodPut).Name("loglevel-set")
+	router.HandleFunc("/stats", a.stats).Methods(http.MethodGet).Name("stats")
+	router.HandleFunc("/routes", a.routes).Methods(http.MethodGet).Name("routes")
+
+	// pprof.Index serves the named profiles (heap, goroutine, etc.)
+	// under its prefix.
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline).Name("pprof-cmdline")
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile).Name("pprof-profile")
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol).Name("pprof-symbol")
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace).Name("pprof-trace")
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index).Name("pprof")
+}
+
+func (a *Admin) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	util.RespondJSON(w, http.StatusOK, logLevel{Level: levelName(a.level)}, util.EmptyHeader)
+}
+
+// putLogLevel changes the log level. The body is either JSON like
+// {"level":"debug"} or the bare level name. Levels are the same as
+// the config file's logging.level.
+func (a *Admin) putLogLevel(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+	if err != nil {
+		util.RespondBad(w, err)
+		return
+	}
+	requested := logLevel{Level: strings.TrimSpace(string(body))}
+	if strings.HasPrefix(requested.Level, "{") {
+		if err := json.Unmarshal(body, &requested); err != nil {
+			util.RespondBad(w, err)
+			return
+		}
+	}
+
+	level, err := config.ParseLevel(requested.Level)
+	if err != nil {
+		util.RespondBad(w, err)
+		return
+	}
+
+	previous := levelName(a.level)
+	a.level.SetLevel(level)
+	log.FromContext(r.Context()).Info("log level changed", "from", previous, "to", levelName(a.level))
+
+	util.RespondJSON(w, http.StatusOK, logLevel{Level: levelName(a.level)}, util.EmptyHeader)
+}
+
+// stats reports runtime statistics and the number of each kind of
+// object in the informer cache.
+func (a *Admin) stats(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	cacheCounts := map[string]interface{}{}
+	for kind, list := range map[string]client.ObjectList{
+		"Account":         &epicv1.AccountList{},
+		"LBServiceGroup":  &epicv1.LBServiceGroupList{},
+		"ServicePrefix":   &epicv1.ServicePrefixList{},
+		"LoadBalancer":    &epicv1.LoadBalancerList{},
+		"RemoteEndpoint":  &epicv1.RemoteEndpointList{},
+		"GWProxy":         &epicv1.GWProxyList{},
+		"GWRoute":         &epicv1.GWRouteList{},
+		"GWEndpointSlice": &epicv1.GWEndpointSliceList{},
+	} {
+		if err := a.cache.List(r.Context(), list); err != nil {
+			cacheCounts[kind] = err.Error()
+			continue
+		}
+		cacheCounts[kind] = meta.LenList(list)
+	}
+
+	util.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"uptime":     time.Since(a.started).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"goVersion":  runtime.Version(),
+		"memory": map[string]uint64{
+			"heapAllocBytes": mem.HeapAlloc,
+			"heapInuseBytes": mem.HeapInuse,
+			"sysBytes":       mem.Sys,
+			"heapObjects":    mem.HeapObjects,
+		},
+		"gc": map[string]interface{}{
+			"count":        mem.NumGC,
+			"pauseTotalNs": mem.PauseTotalNs,
+			"lastPauseNs":  mem.PauseNs[(mem.NumGC+255)%256],
+			"cpuFraction":  mem.GCCPUFraction,
+		},
+		"cache": cacheCounts,
+	}, util.EmptyHeader)
+}
+
+// routes lists every route that every listener serves.
+func (a *Admin) routes(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	infos := []routeInfo{}
+	for _, nr := range a.routers {
+		err := nr.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				// Subrouters created by PathPrefix() without a handler
+				// have a template; routes without a path don't, and
+				// there's nothing useful to show for them.
+				return nil
+			}
+			if route.GetHandler() == nil {
+				return nil
+			}
+			methods, _ := route.GetMethods()
+			infos = append(infos, routeInfo{Listener: nr.listener, Name: route.GetName(), Path: path, Methods: methods})
+			return nil
+		})
+		if err != nil {
+			util.RespondError(w, fmt.Errorf("listing routes for %s: %w", nr.listener, err))
+			return
+		}
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Listener != infos[j].Listener {
+			return infos[i].Listener < infos[j].Listener
+		}
+		return infos[i].Path < infos[j].Path
+	})
+
+	util.RespondJSON(w, http.StatusOK, map[string]interface{}{"routes": infos}, util.EmptyHeader)
+}
+
+// levelName formats a level the way the config file does: zap's
+// names for the standard levels, and logr verbosity numbers for
+// the levels below debug.
+func levelName(level zap.AtomicLevel) string {
+	l := level.Level()
+	if l < zap.DebugLevel {
+		return fmt.Sprintf("%d", -int(l))
+	}
+	return l.String()
+}
@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+const (
+	// maxBodyBytes limits how much of each request body we record.
+	maxBodyBytes = 64 * 1024
+)
+
+var (
+	// kinds maps the collection names in our URLs to the kinds of
+	// objects that they contain.
+	kinds = map[string]string{
+		"accounts":     "Account",
+		"groups":       "LBServiceGroup",
+		"services":     "LoadBalancer",
+		"clusters":     "UpstreamCluster",
+		"endpoints":    "RemoteEndpoint",
+		"proxies":      "GWProxy",
+		"routes":       "GWRoute",
+		"slices":       "GWEndpointSlice",
+		"reservations": "Reservation",
+	}
+)
+
+// Event is one entry in the audit log.
+type Event struct {
+	Time         time.Time       `json:"time"`
+	RequestID    string          `json:"requestID,omitempty"`
+	User         string          `json:"user"`
+	SourceIP     string          `json:"sourceIP"`
+	ForwardedFor []string        `json:"forwardedFor,omitempty"`
+	Method       string          `json:"method"`
+	URI          string          `json:"uri"`
+	Account      string          `json:"account,omitempty"`
+	Kind         string          `json:"kind"`
+	Name         string          `json:"name,omitempty"`
+	Status       int             `json:"status"`
+	Outcome      string          `json:"outcome"`
+	RequestBody  json.RawMessage `json:"requestBody,omitempty"`
+}
+
+// Auditor records mutating API calls to a set of sinks.
+type Auditor struct {
+	policy Policy
+	sinks  []Sink
+}
+
+// NewAuditor configures a new Auditor that writes to sinks
+// according to policy.
+func NewAuditor(policy Policy, sinks ...Sink) *Auditor {
+	return &Auditor{policy: policy, sinks: sinks}
+}
+
+// Close closes the auditor's sinks.
+func (a *Auditor) Close() error {
+	var firstErr error
+	for _, sink := range a.sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Middleware records each POST, PUT, PATCH and DELETE request that
+// the router dispatches. It should run after util.LoggingMiddleware
+// so the request ID is available.
+func (a *Auditor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutating(r.Method) || len(a.sinks) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		kind, name := target(r)
+		level := a.policy.LevelFor(kind)
+		if level == LevelNone {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		event := Event{
+			Time:         time.Now().UTC(),
+			RequestID:    w.Header().Get(util.RequestIDHeader),
+			User:         util.CallerIdentity(r),
+			SourceIP:     util.SourceIP(r),
+			ForwardedFor: util.ForwardedFor(r),
+			Method:       r.Method,
+			URI:          r.RequestURI,
+			Account:      mux.Vars(r)["account"],
+			Kind:         kind,
+			Name:         name,
+		}
+
+		if level == LevelRequest && r.Body != nil {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+			if err == nil {
+				if json.Valid(body) {
+					event.RequestBody = body
+				}
+				// Replace the body so the handler can read it
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			}
+		}
+
+		rec := util.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		event.Status = rec.Status
+		event.Outcome = outcome(rec.Status)
+
+		// Creates don't have the object's name in their URL but they
+		// redirect to it, so we can get it from there.
+		if event.Name == "" {
+			if location := rec.Header().Get("Location"); location != "" {
+				event.Name = path.Base(location)
+			}
+		}
+
+		a.write(r, event)
+	})
+}
+
+func (a *Auditor) write(r *http.Request, event Event) {
+	for _, sink := range a.sinks {
+		if err := sink.Write(event); err != nil {
+			log.FromContext(r.Context()).Error(err, "writing audit event")
+		}
+	}
+}
+
+// target returns the kind and name of the object that r operates
+// on. We figure that out from the route's path template: the last
+// literal segment is the collection, and if it's followed by a
+// variable then that's the object's name.
+func target(r *http.Request) (string, string) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "Unknown", ""
+	}
+	tmpl, err := route.GetPathTemplate()
+	if err != nil {
+		return "Unknown", ""
+	}
+
+	vars := mux.Vars(r)
+	segments := strings.Split(strings.Trim(tmpl, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if isVar(segments[i]) {
+			continue
+		}
+		kind, ok := kinds[segments[i]]
+		if !ok {
+			kind = segments[i]
+		}
+		name := ""
+		if i+1 < len(segments) && isVar(segments[i+1]) {
+			name = vars[strings.SplitN(strings.Trim(segments[i+1], "{}"), ":", 2)[0]]
+		}
+		return kind, name
+	}
+
+	return "Unknown", ""
+}
+
+func isVar(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func outcome(status int) string {
+	if status < http.StatusBadRequest {
+		return "success"
+	}
+	return "failure"
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		path     string
+		wantKind string
+		wantName string
+	}{
+		{"object", "/accounts/{account}/services/{service}", "/api/epic/accounts/root/services/web", "LoadBalancer", "web"},
+		{"collection", "/accounts/{account}/routes", "/api/epic/accounts/root/routes", "GWRoute", ""},
+		{"nested object", "/accounts/{account}/services/{service}/endpoints/{endpoint}", "/api/epic/accounts/root/services/web/endpoints/ep1", "RemoteEndpoint", "ep1"},
+		{"nested collection", "/accounts/{account}/groups/{group}/proxies", "/api/epic/accounts/root/groups/gw/proxies", "GWProxy", ""},
+		{"account", "/accounts/{account}", "/api/epic/accounts/root", "Account", "root"},
+		{"pattern variable", "/accounts/{account}/slices/{slice:[a-z0-9-]+}", "/api/epic/accounts/root/slices/s-1", "GWEndpointSlice", "s-1"},
+		{"unknown collection", "/accounts/{account}/summary", "/api/epic/accounts/root/summary", "summary", ""},
+		{"escaped name", "/accounts/{account}/clusters/{cluster}", "/api/epic/accounts/root/clusters/a%2Fb", "UpstreamCluster", "a%2Fb"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var kind, name string
+			router := mux.NewRouter().UseEncodedPath()
+			router.PathPrefix("/api/epic").Subrouter().HandleFunc(test.template, func(w http.ResponseWriter, r *http.Request) {
+				kind, name = target(r)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, test.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("route %q didn't match %q: status %d", test.template, test.path, rec.Code)
+			}
+			if kind != test.wantKind || name != test.wantName {
+				t.Errorf("target() = (%q, %q), want (%q, %q)", kind, name, test.wantKind, test.wantName)
+			}
+		})
+	}
+}
+
+func TestTargetWithoutRoute(t *testing.T) {
+	kind, name := target(httptest.NewRequest(http.MethodPost, "/accounts/root", nil))
+	if kind != "Unknown" || name != "" {
+		t.Errorf("target() = (%q, %q), want (\"Unknown\", \"\")", kind, name)
+	}
+}
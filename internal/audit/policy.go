@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Level controls how much information is recorded about a request.
+type Level string
+
+const (
+	// LevelNone means don't record the request at all.
+	LevelNone Level = "None"
+
+	// LevelMetadata records who did what to which object, and the
+	// outcome, but not the request body.
+	LevelMetadata Level = "Metadata"
+
+	// LevelRequest records everything that LevelMetadata does plus
+	// the request body.
+	LevelRequest Level = "Request"
+)
+
+// Policy controls the verbosity of the audit log for each resource
+// kind. Kinds that aren't listed use the Default level.
+type Policy struct {
+	Default Level            `json:"default"`
+	Kinds   map[string]Level `json:"kinds,omitempty"`
+}
+
+// DefaultPolicy records metadata about every mutating request.
+func DefaultPolicy() Policy {
+	return Policy{Default: LevelMetadata, Kinds: map[string]Level{}}
+}
+
+// LoadPolicy reads a YAML policy file, e.g.:
+//
+//	default: Metadata
+//	kinds:
+//	  GWRoute: Request
+//	  RemoteEndpoint: None
+func LoadPolicy(path string) (Policy, error) {
+	policy := DefaultPolicy()
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := yaml.UnmarshalStrict(bytes, &policy); err != nil {
+		return policy, fmt.Errorf("parsing audit policy %s: %w", path, err)
+	}
+
+	if err := policy.Default.validate(); err != nil {
+		return policy, err
+	}
+	for _, level := range policy.Kinds {
+		if err := level.validate(); err != nil {
+			return policy, err
+		}
+	}
+
+	return policy, nil
+}
+
+// LevelFor returns the level at which requests for kind are recorded.
+func (p Policy) LevelFor(kind string) Level {
+	if level, ok := p.Kinds[kind]; ok {
+		return level
+	}
+	if p.Default == "" {
+		return LevelMetadata
+	}
+	return p.Default
+}
+
+func (l Level) validate() error {
+	switch l {
+	case LevelNone, LevelMetadata, LevelRequest:
+		return nil
+	}
+	return fmt.Errorf("invalid audit level %q, must be one of %s, %s or %s", l, LevelNone, LevelMetadata, LevelRequest)
+}
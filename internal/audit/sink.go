@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Sink is a destination for audit events.
+type Sink interface {
+	// Write records one event.
+	Write(Event) error
+
+	// Close flushes any buffered events and releases the sink's
+	// resources.
+	Close() error
+}
+
+// writerSink writes events to an io.Writer as JSON lines.
+type writerSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewStdoutSink returns a sink that writes events to stdout, one
+// JSON object per line.
+func NewStdoutSink() Sink {
+	return &writerSink{w: os.Stdout, enc: json.NewEncoder(os.Stdout)}
+}
+
+// NewFileSink returns a sink that appends events to the file at
+// path, one JSON object per line. The file is created if it doesn't
+// exist.
+func NewFileSink(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &writerSink{w: file, enc: json.NewEncoder(file), closer: file}, nil
+}
+
+func (s *writerSink) Write(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(event)
+}
+
+func (s *writerSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// webhookSink POSTs events to an HTTP endpoint. Events are queued
+// and sent by a background goroutine so a slow webhook can't slow
+// down API requests. If the queue fills up then events are dropped
+// (and logged). Events written after Close() are dropped.
+type webhookSink struct {
+	url    string
+	client *http.Client
+	queue  chan Event
+	done   chan struct{}
+
+	// mu guards closed, and closing queue.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookSink returns a sink that POSTs each event as JSON to
+// url.
+func NewWebhookSink(url string) Sink {
+	s := &webhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan Event, 1000),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *webhookSink) Write(event Event) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return fmt.Errorf("audit webhook closed, dropping event")
+	}
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+		return fmt.Errorf("audit webhook queue full, dropping event")
+	}
+}
+
+func (s *webhookSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+func (s *webhookSink) run() {
+	defer close(s.done)
+	logger := log.Log.WithName("audit-webhook")
+
+	for event := range s.queue {
+		if err := s.send(event); err != nil {
+			logger.Error(err, "sending audit event", "url", s.url, "requestID", event.RequestID)
+		}
+	}
+}
+
+func (s *webhookSink) send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("audit webhook returned %s", resp.Status)
+	}
+	return nil
+}
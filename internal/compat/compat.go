@@ -0,0 +1,221 @@
+package compat
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// resourceModelPath is the module that provides our compiled-in
+	// CRD types.
+	resourceModelPath = "epic-gateway.org/resource-model"
+)
+
+// Severity is how serious an Issue is.
+type Severity string
+
+const (
+	// SeverityError means that the web service can't work correctly
+	// with the cluster's CRD, e.g., the CRD doesn't serve the version
+	// that we use or it lacks a field that we set.
+	SeverityError Severity = "error"
+
+	// SeverityWarning means that the CRD has fields that our types
+	// don't know about. We'll drop them when we read and write objects,
+	// which might or might not matter.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is one incompatibility between a CRD and our types.
+type Issue struct {
+	Resource string
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Severity, i.Resource, i.Message)
+}
+
+// Report is the result of a compatibility check.
+type Report struct {
+	// ModelVersion is the version of the resource-model module that
+	// we were built with.
+	ModelVersion string
+
+	Issues []Issue
+}
+
+// Compatible returns true if the report has no errors. Warnings
+// don't count.
+func (r Report) Compatible() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// expected is a resource that we use and the Go type of its spec.
+type expected struct {
+	plural   string
+	specType reflect.Type
+}
+
+var (
+	resources = []expected{
+		{"accounts", reflect.TypeOf(epicv1.Account{}.Spec)},
+		{"lbservicegroups", reflect.TypeOf(epicv1.LBServiceGroup{}.Spec)},
+		{"serviceprefixes", reflect.TypeOf(epicv1.ServicePrefix{}.Spec)},
+		{"loadbalancers", reflect.TypeOf(epicv1.LoadBalancer{}.Spec)},
+		{"remoteendpoints", reflect.TypeOf(epicv1.RemoteEndpoint{}.Spec)},
+		{"gwproxies", reflect.TypeOf(epicv1.GWProxy{}.Spec)},
+		{"gwroutes", reflect.TypeOf(epicv1.GWRoute{}.Spec)},
+		{"gwendpointslices", reflect.TypeOf(epicv1.GWEndpointSlice{}.Spec)},
+	}
+)
+
+// Check reads the EPIC CRDs from the cluster and compares their
+// served versions and spec schemas against our compiled-in types.
+func Check(ctx context.Context, cs clientset.Interface) (Report, error) {
+	report := Report{ModelVersion: modelVersion()}
+
+	for _, res := range resources {
+		crdName := res.plural + "." + epicv1.GroupVersion.Group
+		crd, err := cs.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
+		if err != nil {
+			return report, fmt.Errorf("reading CRD %s: %w", crdName, err)
+		}
+		report.Issues = append(report.Issues, checkCRD(res, crd)...)
+	}
+
+	return report, nil
+}
+
+// checkCRD compares one CRD against the type that we expect.
+func checkCRD(res expected, crd *apiextensionsv1.CustomResourceDefinition) []Issue {
+	issues := []Issue{}
+	wantVersion := epicv1.GroupVersion.Version
+
+	var version *apiextensionsv1.CustomResourceDefinitionVersion
+	served := []string{}
+	for i := range crd.Spec.Versions {
+		if crd.Spec.Versions[i].Served {
+			served = append(served, crd.Spec.Versions[i].Name)
+		}
+		if crd.Spec.Versions[i].Name == wantVersion {
+			version = &crd.Spec.Versions[i]
+		}
+	}
+	if version == nil || !version.Served {
+		return append(issues, Issue{
+			Resource: res.plural,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("version %s not served (served versions: %v)", wantVersion, served),
+		})
+	}
+
+	if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+		return issues
+	}
+	spec, ok := version.Schema.OpenAPIV3Schema.Properties["spec"]
+	if !ok || (spec.XPreserveUnknownFields != nil && *spec.XPreserveUnknownFields) {
+		// The CRD accepts anything so there's nothing to compare.
+		return issues
+	}
+
+	goFields := jsonFields(res.specType)
+	for _, name := range goFields {
+		if _, ok := spec.Properties[name]; !ok {
+			issues = append(issues, Issue{
+				Resource: res.plural,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("spec field %q is in resource-model %s but not in the CRD schema", name, modelVersion()),
+			})
+		}
+	}
+
+	known := map[string]bool{}
+	for _, name := range goFields {
+		known[name] = true
+	}
+	extra := []string{}
+	for name := range spec.Properties {
+		if !known[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		issues = append(issues, Issue{
+			Resource: res.plural,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("spec field %q is in the CRD schema but not in resource-model %s", name, modelVersion()),
+		})
+	}
+
+	return issues
+}
+
+// jsonFields returns the JSON names of t's fields, following inline
+// and embedded structs the same way that encoding/json does.
+func jsonFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" || strings.Contains(tag, ",inline") {
+			fields = append(fields, jsonFields(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
+// modelVersion returns the version of the resource-model module that
+// this binary was built with.
+func modelVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown)"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == resourceModelPath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "(unknown)"
+}
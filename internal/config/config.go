@@ -0,0 +1,652 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"acnodal.io/epic/web-service/internal/audit"
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/server"
+	"acnodal.io/epic/web-service/internal/tracing"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+const (
+	// APIVersion is the version of the config file format.
+	APIVersion = "webservice.epic.acnodal.io/v1alpha1"
+
+	// Kind is the kind of the config file.
+	Kind = "WebServiceConfig"
+
+	// EnvPrefix is the prefix of the environment variables that
+	// override config file settings.
+	EnvPrefix = "EPIC_WS_"
+
+	// DefaultURLRoot is the common root of this service's URLs.
+	DefaultURLRoot = "/api/epic"
+)
+
+// Listener protocols.
+const (
+	ProtocolHTTP  = "http"
+	ProtocolHTTPS = "https"
+	ProtocolH2C   = "h2c"
+)
+
+// Route groups that a listener can serve.
+const (
+	// RoutesAPI is the tenant-facing REST API.
+	RoutesAPI = "api"
+
+	// RoutesHealth is the /healthz, /livez and /readyz endpoints.
+	RoutesHealth = "health"
+
+	// RoutesAdmin is the operational API: pprof, log level, stats,
+	// etc. It's not under URLRoot and must never be exposed to
+	// tenants.
+	RoutesAdmin = "admin"
+
+	// RoutesManagement is the account, group and prefix management
+	// API under URLRoot/admin. It's protected by the management
+	// token.
+	RoutesManagement = "management"
+)
+
+// RouteGroups are the valid values of Listener.Routes.
+var RouteGroups = []string{RoutesAPI, RoutesHealth, RoutesAdmin, RoutesManagement}
+
+// Config is the web service's configuration. It can be loaded from
+// a YAML file, and each setting can be overridden by an environment
+// variable whose name is EnvPrefix followed by the setting's path in
+// upper snake case, e.g., EPIC_WS_TIMEOUTS_SHUTDOWN. Command-line
+// flags override both.
+type Config struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// ListenAddr is the address that the web service binds to if
+	// Listeners is empty.
+	ListenAddr string `json:"listenAddr"`
+
+	// Listeners are the web service's listeners. If empty, the
+	// service listens on ListenAddr, using HTTPS if TLS is enabled,
+	// and serves the api and health routes, and the management routes
+	// if Management is enabled and public.
+	Listeners []Listener `json:"listeners,omitempty"`
+
+	// AdminAddr is the address that the admin API binds to. It
+	// should be a loopback address. If empty, the admin API is served
+	// only on Listeners that include the admin route group.
+	AdminAddr string `json:"adminAddr"`
+
+	// URLRoot is the common root of the web service's URLs.
+	URLRoot string `json:"urlRoot"`
+
+	// ExternalURL is the base of the links in our responses, e.g.,
+	// "https://gateway.example.com/epic-ws". If empty, links are
+	// based on each request, and its X-Forwarded-* headers if it came
+	// from one of the TrustedProxies.
+	ExternalURL string `json:"externalURL"`
+
+	// TrustedProxies are the CIDRs of the proxies whose X-Remote-User
+	// header identifies the caller and whose X-Forwarded-* headers are
+	// used in links. If empty, the headers are ignored.
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+
+	// MetricsAddr is the address that the metrics endpoint binds to.
+	MetricsAddr string `json:"metricsAddr"`
+
+	// HealthProbeAddr is the address that the manager's liveness and
+	// readiness endpoints bind to.
+	HealthProbeAddr string `json:"healthProbeAddr"`
+
+	// WebhookPort is the port that the manager's webhook server binds
+	// to.
+	WebhookPort int `json:"webhookPort"`
+
+	// RequireCompatibleCRDs causes the service to refuse to start if
+	// the cluster's CRDs don't match the compiled-in resource model.
+	RequireCompatibleCRDs bool `json:"requireCompatibleCRDs"`
+
+	LeaderElection LeaderElection `json:"leaderElection"`
+	Timeouts       Timeouts       `json:"timeouts"`
+	Logging        Logging        `json:"logging"`
+	Retry          Retry          `json:"retry"`
+	Tracing        Tracing        `json:"tracing"`
+	Audit          Audit          `json:"audit"`
+	TLS            TLS            `json:"tls"`
+	Redaction      Redaction      `json:"redaction"`
+	Management     Management     `json:"management"`
+	Reservations   Reservations   `json:"reservations"`
+}
+
+// Reservations configures how long the addresses of deleted
+// LoadBalancers and GWProxies are held. See db.ReservationOptions.
+type Reservations struct {
+	TTL    metav1.Duration `json:"ttl"`
+	MaxTTL metav1.Duration `json:"maxTTL"`
+}
+
+// Management configures the account, group and prefix management
+// API.
+type Management struct {
+	// TokenFile holds the bearer token that management clients must
+	// present. If empty, the management API is disabled.
+	TokenFile string `json:"tokenFile"`
+
+	// DefaultGroups are the LBServiceGroups that are created with each
+	// account unless the request lists its own.
+	DefaultGroups []model.GroupTemplate `json:"defaultGroups"`
+
+	// AccountClusterRole, if not empty, is bound in each new account's
+	// namespace to the Kubernetes group "epic:account:<name>".
+	AccountClusterRole string `json:"accountClusterRole"`
+
+	// Public serves the management API alongside the tenant API. If
+	// false it's served only on the admin listener (AdminAddr) and on
+	// Listeners that don't serve the api routes.
+	Public bool `json:"public"`
+}
+
+// Enabled indicates whether the management API is configured.
+func (m Management) Enabled() bool {
+	return m.TokenFile != ""
+}
+
+// Redaction configures which Kubernetes metadata clients see in
+// responses. See model.RedactionPolicy.
+type Redaction struct {
+	MetadataFields       []string `json:"metadataFields"`
+	InternalLabelDomains []string `json:"internalLabelDomains"`
+}
+
+// Listener configures one of the web service's listeners.
+type Listener struct {
+	// Name identifies the listener in logs.
+	Name string `json:"name"`
+
+	// Network is "tcp" or "unix".
+	Network string `json:"network"`
+
+	// Address is a host:port for TCP or a file path for Unix.
+	Address string `json:"address"`
+
+	// Protocol is "http", "https" or "h2c". "https" uses the TLS
+	// settings.
+	Protocol string `json:"protocol"`
+
+	// Routes are the route groups that this listener serves.
+	Routes []string `json:"routes"`
+}
+
+// LeaderElection configures the manager's leader election.
+type LeaderElection struct {
+	Enabled bool   `json:"enabled"`
+	ID      string `json:"id"`
+}
+
+// Timeouts configures the web server and the db layer's
+// read-your-writes timeout.
+type Timeouts struct {
+	ReadHeader     metav1.Duration `json:"readHeader"`
+	Read           metav1.Duration `json:"read"`
+	Write          metav1.Duration `json:"write"`
+	Idle           metav1.Duration `json:"idle"`
+	Shutdown       metav1.Duration `json:"shutdown"`
+	ReadYourWrites metav1.Duration `json:"readYourWrites"`
+}
+
+// Logging configures the logger.
+type Logging struct {
+	// Development enables zap's development mode: human-friendly
+	// console output, stack traces on warnings, etc.
+	Development bool `json:"development"`
+
+	// Level is "debug", "info" or "error", or a number N which
+	// enables logr V(N) messages.
+	Level string `json:"level"`
+}
+
+// Retry configures the db layer's retry policy. See db.Options.
+type Retry struct {
+	InitialInterval metav1.Duration `json:"initialInterval"`
+	Multiplier      float64         `json:"multiplier"`
+	MaxInterval     metav1.Duration `json:"maxInterval"`
+	Jitter          float64         `json:"jitter"`
+	MaxElapsed      metav1.Duration `json:"maxElapsed"`
+	MaxAttempts     int             `json:"maxAttempts"`
+	RetryOn         []string        `json:"retryOn"`
+}
+
+// Tracing configures the OpenTelemetry exporters. See
+// tracing.Options.
+type Tracing struct {
+	OTLPEndpoint string `json:"otlpEndpoint"`
+	OTLPInsecure bool   `json:"otlpInsecure"`
+	Stdout       bool   `json:"stdout"`
+}
+
+// Audit configures the audit log sinks and policy.
+type Audit struct {
+	File    string `json:"file"`
+	Stdout  bool   `json:"stdout"`
+	Webhook string `json:"webhook"`
+	Policy  string `json:"policy"`
+}
+
+// TLS configures HTTPS serving. The certificate comes either from a
+// pair of PEM files or from a "kubernetes.io/tls" Secret, and is
+// reloaded when it changes. If neither is set, the service serves
+// plain HTTP.
+type TLS struct {
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+
+	// Secret is the "namespace/name" of the Secret.
+	Secret string `json:"secret"`
+
+	// MinVersion is the minimum TLS version: "1.0", "1.1", "1.2" or
+	// "1.3".
+	MinVersion string `json:"minVersion"`
+
+	// CipherSuites are IANA cipher suite names. If empty, Go's
+	// defaults are used. TLS 1.3 suites aren't configurable.
+	CipherSuites []string `json:"cipherSuites"`
+}
+
+// Enabled indicates whether TLS is configured.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.Secret != ""
+}
+
+// SecretKey splits Secret into its namespace and name.
+func (t TLS) SecretKey() (namespace string, name string, err error) {
+	parts := strings.Split(t.Secret, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid tls.secret %q, expected namespace/name", t.Secret)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Default returns the default configuration.
+func Default() *Config {
+	dbOpts := db.DefaultOptions()
+	reservationOpts := db.DefaultReservationOptions()
+	redaction := model.DefaultRedactionPolicy()
+
+	return &Config{
+		APIVersion:      APIVersion,
+		Kind:            Kind,
+		ListenAddr:      ":8080",
+		URLRoot:         DefaultURLRoot,
+		MetricsAddr:     ":7472",
+		HealthProbeAddr: ":7473",
+		AdminAddr:       "127.0.0.1:7474",
+		WebhookPort:     9443,
+		LeaderElection: LeaderElection{
+			Enabled: false,
+			ID:      "1cb3972f.acnodal.io",
+		},
+		Timeouts: Timeouts{
+			ReadHeader:     metav1.Duration{Duration: server.DefaultReadHeaderTimeout},
+			Read:           metav1.Duration{Duration: server.DefaultReadTimeout},
+			Write:          metav1.Duration{Duration: server.DefaultWriteTimeout},
+			Idle:           metav1.Duration{Duration: server.DefaultIdleTimeout},
+			Shutdown:       metav1.Duration{Duration: server.DefaultShutdownTimeout},
+			ReadYourWrites: metav1.Duration{Duration: 2 * time.Second},
+		},
+		Logging: Logging{
+			Development: true,
+			Level:       "info",
+		},
+		Retry: Retry{
+			InitialInterval: metav1.Duration{Duration: dbOpts.InitialInterval},
+			Multiplier:      dbOpts.Multiplier,
+			MaxInterval:     metav1.Duration{Duration: dbOpts.MaxInterval},
+			Jitter:          dbOpts.Jitter,
+			MaxElapsed:      metav1.Duration{Duration: dbOpts.MaxElapsed},
+			MaxAttempts:     dbOpts.MaxAttempts,
+			RetryOn:         dbOpts.RetryOn,
+		},
+		TLS: TLS{
+			MinVersion:   "1.2",
+			CipherSuites: []string{},
+		},
+		Redaction: Redaction{
+			MetadataFields:       redaction.Fields,
+			InternalLabelDomains: redaction.InternalLabelDomains,
+		},
+		Management: Management{
+			DefaultGroups: []model.GroupTemplate{},
+		},
+		Reservations: Reservations{
+			TTL:    metav1.Duration{Duration: reservationOpts.TTL},
+			MaxTTL: metav1.Duration{Duration: reservationOpts.MaxTTL},
+		},
+	}
+}
+
+// LoadFile reads the YAML file at path into c. Settings that aren't
+// in the file keep their current values.
+func (c *Config) LoadFile(path string) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(bytes, c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that c makes sense.
+func (c *Config) Validate() error {
+	if c.APIVersion != APIVersion {
+		return fmt.Errorf("unsupported config apiVersion %q, expected %q", c.APIVersion, APIVersion)
+	}
+	if c.Kind != Kind {
+		return fmt.Errorf("unsupported config kind %q, expected %q", c.Kind, Kind)
+	}
+
+	if err := c.validateListeners(); err != nil {
+		return err
+	}
+
+	if c.AdminAddr != "" {
+		if _, _, err := net.SplitHostPort(c.AdminAddr); err != nil {
+			return fmt.Errorf("invalid adminAddr %q: %w", c.AdminAddr, err)
+		}
+		for _, l := range c.Listeners {
+			if l.Name == "admin" {
+				return fmt.Errorf("listener name \"admin\" is reserved for adminAddr")
+			}
+		}
+	}
+
+	for name, addr := range map[string]string{"listenAddr": c.ListenAddr, "healthProbeAddr": c.HealthProbeAddr} {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, addr, err)
+		}
+	}
+	// The manager allows "0" to disable the metrics endpoint.
+	if c.MetricsAddr != "0" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			return fmt.Errorf("invalid metricsAddr %q: %w", c.MetricsAddr, err)
+		}
+	}
+
+	if !strings.HasPrefix(c.URLRoot, "/") || strings.HasSuffix(c.URLRoot, "/") {
+		return fmt.Errorf("urlRoot %q must start with \"/\" and must not end with \"/\"", c.URLRoot)
+	}
+	if c.ExternalURL != "" {
+		u, err := url.Parse(c.ExternalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("externalURL %q must be an absolute http or https URL", c.ExternalURL)
+		}
+	}
+	if _, err := util.ParseCIDRs(c.TrustedProxies); err != nil {
+		return fmt.Errorf("trustedProxies: %w", err)
+	}
+	if c.WebhookPort < 1 || c.WebhookPort > 65535 {
+		return fmt.Errorf("invalid webhookPort %d", c.WebhookPort)
+	}
+	if c.LeaderElection.Enabled && c.LeaderElection.ID == "" {
+		return fmt.Errorf("leaderElection.id is required when leader election is enabled")
+	}
+
+	for name, d := range map[string]metav1.Duration{
+		"timeouts.readHeader":     c.Timeouts.ReadHeader,
+		"timeouts.read":           c.Timeouts.Read,
+		"timeouts.write":          c.Timeouts.Write,
+		"timeouts.idle":           c.Timeouts.Idle,
+		"timeouts.shutdown":       c.Timeouts.Shutdown,
+		"timeouts.readYourWrites": c.Timeouts.ReadYourWrites,
+	} {
+		if d.Duration <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+
+	if _, err := c.ZapLevel(); err != nil {
+		return err
+	}
+
+	if err := c.DBOptions().Validate(); err != nil {
+		return err
+	}
+
+	if err := c.ReservationOptions().Validate(); err != nil {
+		return fmt.Errorf("reservations: %w", err)
+	}
+
+	if err := c.TLS.validate(); err != nil {
+		return err
+	}
+
+	if err := c.RedactionPolicy().Validate(); err != nil {
+		return fmt.Errorf("redaction: %w", err)
+	}
+
+	for i, group := range c.Management.DefaultGroups {
+		if group.Name == "" || group.ServicePrefix == "" {
+			return fmt.Errorf("management.defaultGroups[%d] needs a name and a servicePrefix", i)
+		}
+	}
+
+	if c.Audit.Policy != "" {
+		if _, err := audit.LoadPolicy(c.Audit.Policy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EffectiveListeners returns the listeners that the service should
+// run: either the configured Listeners or the default listener on
+// ListenAddr, plus the admin listener on AdminAddr.
+func (c *Config) EffectiveListeners() []Listener {
+	listeners := append([]Listener{}, c.Listeners...)
+
+	if len(listeners) == 0 {
+		protocol := ProtocolHTTP
+		if c.TLS.Enabled() {
+			protocol = ProtocolHTTPS
+		}
+		listeners = append(listeners, Listener{
+			Name:     "web-service",
+			Network:  server.NetworkTCP,
+			Address:  c.ListenAddr,
+			Protocol: protocol,
+			Routes:   []string{RoutesAPI, RoutesHealth},
+		})
+		if c.Management.Enabled() && c.Management.Public {
+			listeners[0].Routes = append(listeners[0].Routes, RoutesManagement)
+		}
+	}
+
+	if c.AdminAddr != "" {
+		admin := Listener{
+			Name:     "admin",
+			Network:  server.NetworkTCP,
+			Address:  c.AdminAddr,
+			Protocol: ProtocolHTTP,
+			Routes:   []string{RoutesAdmin},
+		}
+		if c.Management.Enabled() && !c.Management.Public {
+			admin.Routes = append(admin.Routes, RoutesManagement)
+		}
+		listeners = append(listeners, admin)
+	}
+
+	return listeners
+}
+
+func (c *Config) validateListeners() error {
+	names := map[string]struct{}{}
+	for i, l := range c.Listeners {
+		if l.Name == "" {
+			return fmt.Errorf("listeners[%d].name is required", i)
+		}
+		if _, dup := names[l.Name]; dup {
+			return fmt.Errorf("listener name %q is used more than once", l.Name)
+		}
+		names[l.Name] = struct{}{}
+
+		switch l.Network {
+		case server.NetworkTCP:
+			if _, _, err := net.SplitHostPort(l.Address); err != nil {
+				return fmt.Errorf("listener %s has invalid address %q: %w", l.Name, l.Address, err)
+			}
+		case server.NetworkUnix:
+			if !filepath.IsAbs(l.Address) {
+				return fmt.Errorf("listener %s address %q must be an absolute path", l.Name, l.Address)
+			}
+		default:
+			return fmt.Errorf("listener %s has unknown network %q, expected %s or %s", l.Name, l.Network, server.NetworkTCP, server.NetworkUnix)
+		}
+
+		switch l.Protocol {
+		case ProtocolHTTP, ProtocolH2C:
+		case ProtocolHTTPS:
+			if !c.TLS.Enabled() {
+				return fmt.Errorf("listener %s uses https but tls.certFile or tls.secret isn't set", l.Name)
+			}
+		default:
+			return fmt.Errorf("listener %s has unknown protocol %q, expected %s, %s or %s", l.Name, l.Protocol, ProtocolHTTP, ProtocolHTTPS, ProtocolH2C)
+		}
+
+		if len(l.Routes) == 0 {
+			return fmt.Errorf("listener %s must serve at least one route group", l.Name)
+		}
+		for _, group := range l.Routes {
+			if !contains(RouteGroups, group) {
+				return fmt.Errorf("listener %s has unknown route group %q, expected one of %s", l.Name, group, strings.Join(RouteGroups, ", "))
+			}
+			if group == RoutesManagement && !c.Management.Enabled() {
+				return fmt.Errorf("listener %s serves the management routes but management.tokenFile isn't set", l.Name)
+			}
+		}
+		if contains(l.Routes, RoutesManagement) && contains(l.Routes, RoutesAPI) && !c.Management.Public {
+			return fmt.Errorf("listener %s serves the management routes with the api routes but management.public isn't set", l.Name)
+		}
+	}
+
+	if c.Management.Enabled() {
+		served := false
+		for _, l := range c.EffectiveListeners() {
+			served = served || contains(l.Routes, RoutesManagement)
+		}
+		if !served {
+			return fmt.Errorf("management.tokenFile is set but no listener serves the management routes; set adminAddr or management.public, or add them to a listener")
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TLS) validate() error {
+	if t.CertFile != "" && t.Secret != "" {
+		return fmt.Errorf("tls.certFile and tls.secret are mutually exclusive")
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls.certFile and tls.keyFile must be set together")
+	}
+	if t.Secret != "" {
+		if _, _, err := t.SecretKey(); err != nil {
+			return err
+		}
+	}
+	if _, err := server.TLSVersion(t.MinVersion); err != nil {
+		return err
+	}
+	if _, err := server.CipherSuites(t.CipherSuites); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ZapLevel converts the logging level into a zap level.
+func (c *Config) ZapLevel() (zapcore.Level, error) {
+	return ParseLevel(c.Logging.Level)
+}
+
+// ParseLevel converts a logging level name like "debug", or a logr
+// verbosity like "2", into a zap level.
+func ParseLevel(name string) (zapcore.Level, error) {
+	// logr verbosity N corresponds to zap level -N
+	if v, err := strconv.Atoi(name); err == nil {
+		if v < 0 {
+			return 0, fmt.Errorf("invalid logging level %q", name)
+		}
+		return zapcore.Level(-v), nil
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid logging level %q", name)
+	}
+	return level, nil
+}
+
+// DBOptions returns the db layer's retry options.
+func (c *Config) DBOptions() db.Options {
+	return db.Options{
+		InitialInterval: c.Retry.InitialInterval.Duration,
+		Multiplier:      c.Retry.Multiplier,
+		MaxInterval:     c.Retry.MaxInterval.Duration,
+		Jitter:          c.Retry.Jitter,
+		MaxElapsed:      c.Retry.MaxElapsed.Duration,
+		MaxAttempts:     c.Retry.MaxAttempts,
+		RetryOn:         c.Retry.RetryOn,
+	}
+}
+
+// ReservationOptions returns the address reservation options.
+func (c *Config) ReservationOptions() db.ReservationOptions {
+	return db.ReservationOptions{
+		TTL:    c.Reservations.TTL.Duration,
+		MaxTTL: c.Reservations.MaxTTL.Duration,
+	}
+}
+
+// RedactionPolicy returns the response metadata redaction policy.
+func (c *Config) RedactionPolicy() model.RedactionPolicy {
+	return model.RedactionPolicy{
+		Fields:               c.Redaction.MetadataFields,
+		InternalLabelDomains: c.Redaction.InternalLabelDomains,
+	}
+}
+
+// TracingOptions returns the tracing options.
+func (c *Config) TracingOptions() tracing.Options {
+	return tracing.Options{
+		OTLPEndpoint: c.Tracing.OTLPEndpoint,
+		OTLPInsecure: c.Tracing.OTLPInsecure,
+		Stdout:       c.Tracing.Stdout,
+	}
+}
+
+// YAML returns c in YAML format.
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
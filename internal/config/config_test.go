@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManagementListener(t *testing.T) {
+	tests := []struct {
+		name   string
+		public bool
+		admin  string
+		want   map[string][]string
+	}{
+		{"admin listener", false, "127.0.0.1:7474", map[string][]string{
+			"web-service": {RoutesAPI, RoutesHealth},
+			"admin":       {RoutesAdmin, RoutesManagement},
+		}},
+		{"public", true, "127.0.0.1:7474", map[string][]string{
+			"web-service": {RoutesAPI, RoutesHealth, RoutesManagement},
+			"admin":       {RoutesAdmin},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.AdminAddr = test.admin
+			cfg.Management.TokenFile = "/etc/epic/token"
+			cfg.Management.Public = test.public
+			if err := cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string][]string{}
+			for _, l := range cfg.EffectiveListeners() {
+				got[l.Name] = l.Routes
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("routes = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestManagementListenerInvalid(t *testing.T) {
+	// Nowhere to serve it
+	cfg := Default()
+	cfg.AdminAddr = ""
+	cfg.Management.TokenFile = "/etc/epic/token"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted a management API that isn't served")
+	}
+
+	// With the tenant API but not public
+	cfg = Default()
+	cfg.Management.TokenFile = "/etc/epic/token"
+	cfg.Listeners = []Listener{{Name: "tenants", Network: "tcp", Address: ":8080", Protocol: ProtocolHTTP, Routes: []string{RoutesAPI, RoutesManagement}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted the management routes on the api listener")
+	}
+	cfg.Management.Public = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %s with management.public set", err)
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// env returns a lookup function that reads from vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"listenAddr", "LISTEN_ADDR"},
+		{"urlRoot", "URL_ROOT"},
+		{"otlpEndpoint", "OTLP_ENDPOINT"},
+		{"readYourWrites", "READ_YOUR_WRITES"},
+		{"maxTTL", "MAX_TTL"},
+		{"externalURL", "EXTERNAL_URL"},
+		{"stdout", "STDOUT"},
+	}
+
+	for _, test := range tests {
+		if got := envName(test.name); got != test.want {
+			t.Errorf("envName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		vars  map[string]string
+		check func(*Config) interface{}
+		want  interface{}
+	}{
+		{"string", map[string]string{"EPIC_WS_LISTEN_ADDR": ":9090"}, func(c *Config) interface{} { return c.ListenAddr }, ":9090"},
+		{"nested string", map[string]string{"EPIC_WS_TRACING_OTLP_ENDPOINT": "otel:4318"}, func(c *Config) interface{} { return c.Tracing.OTLPEndpoint }, "otel:4318"},
+		{"bool", map[string]string{"EPIC_WS_LEADER_ELECTION_ENABLED": "true"}, func(c *Config) interface{} { return c.LeaderElection.Enabled }, true},
+		{"int", map[string]string{"EPIC_WS_WEBHOOK_PORT": "9000"}, func(c *Config) interface{} { return c.WebhookPort }, 9000},
+		{"float", map[string]string{"EPIC_WS_RETRY_JITTER": "0.5"}, func(c *Config) interface{} { return c.Retry.Jitter }, 0.5},
+		{"duration", map[string]string{"EPIC_WS_TIMEOUTS_READ_YOUR_WRITES": "3s"}, func(c *Config) interface{} { return c.Timeouts.ReadYourWrites.Duration }, 3 * time.Second},
+		{"list", map[string]string{"EPIC_WS_RETRY_RETRY_ON": "conflict, ,timeout"}, func(c *Config) interface{} { return c.Retry.RetryOn }, []string{"conflict", "timeout"}},
+		{"empty list", map[string]string{"EPIC_WS_TRUSTED_PROXIES": ""}, func(c *Config) interface{} { return c.TrustedProxies }, []string{}},
+		{"unset", map[string]string{}, func(c *Config) interface{} { return c.ListenAddr }, ":8080"},
+		{"wrong prefix", map[string]string{"LISTEN_ADDR": ":9090"}, func(c *Config) interface{} { return c.ListenAddr }, ":8080"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Default()
+			if err := cfg.ApplyEnv(env(test.vars)); err != nil {
+				t.Fatal(err)
+			}
+			if got := test.check(cfg); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	tests := []map[string]string{
+		{"EPIC_WS_LEADER_ELECTION_ENABLED": "maybe"},
+		{"EPIC_WS_WEBHOOK_PORT": "http"},
+		{"EPIC_WS_RETRY_MULTIPLIER": "double"},
+		{"EPIC_WS_TIMEOUTS_SHUTDOWN": "30"},
+	}
+
+	for _, vars := range tests {
+		if err := Default().ApplyEnv(env(vars)); err == nil {
+			t.Errorf("ApplyEnv(%v) succeeded, want error", vars)
+		}
+	}
+}
+
+// TestEnvOverridesFile checks that environment variables override
+// the config file, and that settings that aren't in the environment
+// keep the file's values.
+func TestEnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `apiVersion: ` + APIVersion + `
+kind: ` + Kind + `
+listenAddr: ":1111"
+urlRoot: /from-file
+`
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ApplyEnv(env(map[string]string{"EPIC_WS_LISTEN_ADDR": ":2222"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.ListenAddr != ":2222" {
+		t.Errorf("listenAddr = %q, want the environment's \":2222\"", cfg.ListenAddr)
+	}
+	if cfg.URLRoot != "/from-file" {
+		t.Errorf("urlRoot = %q, want the file's \"/from-file\"", cfg.URLRoot)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %s", err)
+	}
+}
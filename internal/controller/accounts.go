@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// AccountAdmin implements the account management API. It's meant for
+// operators and automation, not tenants, so it must be mounted on a
+// router that authenticates its clients.
+type AccountAdmin struct {
+	client        client.Client
+	router        *mux.Router
+	recorder      record.EventRecorder
+	defaultGroups []model.GroupTemplate
+	clusterRole   string
+}
+
+// AccountCreateRequest contains the data from a web service request
+// to create an Account. If Groups is nil then the default groups are
+// created.
+type AccountCreateRequest struct {
+	Account epicv1.Account
+	Groups  []model.GroupTemplate
+}
+
+// GroupCreateRequest contains the data from a web service request to
+// create an LBServiceGroup.
+type GroupCreateRequest struct {
+	Group model.GroupTemplate
+}
+
+func (a *AccountAdmin) create(w http.ResponseWriter, r *http.Request) {
+	var body AccountCreateRequest
+	log := log.FromContext(r.Context())
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Error(err, "POST account failed")
+		util.RespondBad(w, err)
+		return
+	}
+
+	// The account's name is part of its namespace's name so it has to
+	// be a valid label.
+	name := body.Account.Name
+	if errs := validation.IsDNS1123Label(epicv1.AccountNamespace(name)); name == "" || len(errs) > 0 {
+		err := fmt.Errorf("invalid account name %q: %s", name, strings.Join(errs, ", "))
+		log.Error(err, "POST account failed")
+		util.RespondBad(w, err)
+		return
+	}
+	log = log.WithValues("account", name)
+
+	groups := body.Groups
+	if groups == nil {
+		groups = a.defaultGroups
+	}
+	for _, group := range groups {
+		if group.Name == "" || group.ServicePrefix == "" {
+			err := fmt.Errorf("group %q needs a name and a servicePrefix", group.Name)
+			log.Error(err, "POST account failed")
+			util.RespondBad(w, err)
+			return
+		}
+	}
+
+	selfURL, err := util.RouteURL(r, a.router, "admin-account", "account", name)
+	if err != nil {
+		log.Error(err, "POST account failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	// Callers can't set the namespace or labels.
+	account := epicv1.Account{Spec: body.Account.Spec}
+	account.Name = name
+	err = db.CreateAccount(r.Context(), a.client, db.AccountSetup{Account: account, Groups: groups, ClusterRole: a.clusterRole})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			log.Info("POST account 409/duplicate")
+			util.RespondConflict(
+				w,
+				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL}},
+				map[string]string{"Location": selfURL},
+			)
+			return
+		}
+
+		log.Error(err, "POST account failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	log.Info("POST account OK", "groups", len(groups))
+	recordEvent(a.recorder, r, accountRef(r.Context(), a.client, name), corev1.EventTypeNormal, reasonCreated, "Account %s %s", name, pastTense(reasonCreated))
+	http.Redirect(w, r, selfURL, http.StatusFound)
+}
+
+func (a *AccountAdmin) list(w http.ResponseWriter, r *http.Request) {
+	log := log.FromContext(r.Context())
+
+	accounts, err := db.ListAccounts(r.Context(), a.client)
+	if err != nil {
+		log.Error(err, "GET accounts failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	list := model.NewAccountList()
+	if list.Links, err = routeLinks(r, a.router, map[string]route{
+		"self": {"admin-accounts", nil},
+	}); err != nil {
+		log.Error(err, "GET accounts failed")
+		util.RespondError(w, err)
+		return
+	}
+	for _, account := range accounts {
+		if account.Links, err = routeLinks(r, a.router, map[string]route{
+			"self": {"admin-account", []string{"account", account.Account.Name}},
+		}); err != nil {
+			log.Error(err, "GET accounts failed")
+			util.RespondError(w, err)
+			return
+		}
+		model.Redact(&account)
+		list.Accounts = append(list.Accounts, account)
+	}
+
+	log.Info("GET accounts OK", "count", len(list.Accounts))
+	util.RespondJSON(w, http.StatusOK, list, util.EmptyHeader)
+}
+
+func (a *AccountAdmin) show(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+	account, err := db.ReadAccount(r.Context(), a.client, vars["account"])
+	if err != nil {
+		log.Error(err, "GET account failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	if account.Links, err = routeLinks(r, a.router, map[string]route{
+		"self":     {"admin-account", []string{"account", vars["account"]}},
+		"accounts": {"admin-accounts", nil},
+	}); err != nil {
+		log.Error(err, "GET account failed")
+		util.RespondError(w, err)
+		return
+	}
+	log.Info("GET account OK")
+	respondObject(w, r, account)
+}
+
+// del deletes an account and everything in it. The response lists
+// how many of each kind of object are being deleted. If the "dryRun"
+// query parameter is true then nothing is deleted.
+func (a *AccountAdmin) del(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	dryRun := false
+	if param := r.URL.Query().Get("dryRun"); param != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(param); err != nil {
+			log.Error(err, "DELETE account failed")
+			util.RespondBad(w, fmt.Errorf("invalid dryRun %q", param))
+			return
+		}
+	}
+
+	deleted, err := db.DeleteAccount(r.Context(), a.client, vars["account"], dryRun)
+	if err != nil {
+		log.Error(err, "DELETE account failed")
+		switch {
+		case errors.IsNotFound(err):
+			util.RespondNotFound(w, err)
+		case err == db.ErrNotAccountNamespace:
+			util.RespondProblem(w, http.StatusConflict, err.Error())
+		default:
+			util.RespondError(w, err)
+		}
+		return
+	}
+
+	report := model.AccountDeletion{Account: vars["account"], DryRun: dryRun, Deleted: deleted}
+	if dryRun {
+		log.Info("DELETE account dry run OK", "deleted", deleted)
+		util.RespondJSON(w, http.StatusOK, report, util.EmptyHeader)
+		return
+	}
+
+	// Kubernetes deletes the namespace's contents in the background.
+	log.Info("DELETE account OK", "deleted", deleted)
+	util.RespondJSON(w, http.StatusAccepted, report, util.EmptyHeader)
+}
+
+func (a *AccountAdmin) createGroup(w http.ResponseWriter, r *http.Request) {
+	var body GroupCreateRequest
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Error(err, "POST group failed")
+		util.RespondBad(w, err)
+		return
+	}
+	if errs := validation.IsDNS1123Subdomain(body.Group.Name); len(errs) > 0 {
+		err := fmt.Errorf("invalid group name %q: %s", body.Group.Name, strings.Join(errs, ", "))
+		log.Error(err, "POST group failed")
+		util.RespondBad(w, err)
+		return
+	}
+	log = log.WithValues("group", body.Group.Name)
+
+	// 404 if the account doesn't exist
+	if _, err := db.ReadAccount(r.Context(), a.client, vars["account"]); err != nil {
+		log.Error(err, "POST group failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+	if !a.checkPrefix(w, r, body.Group.ServicePrefix) {
+		return
+	}
+
+	selfURL, err := util.RouteURL(r, a.router, "admin-group", "account", vars["account"], "group", body.Group.Name)
+	if err != nil {
+		log.Error(err, "POST group failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	if err := db.CreateGroup(r.Context(), a.client, vars["account"], body.Group); err != nil {
+		if errors.IsAlreadyExists(err) {
+			log.Info("POST group 409/duplicate")
+			util.RespondConflict(
+				w,
+				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL}},
+				map[string]string{"Location": selfURL},
+			)
+			return
+		}
+
+		log.Error(err, "POST group failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	log.Info("POST group OK", "group", body.Group)
+	recordEvent(a.recorder, r, accountRef(r.Context(), a.client, vars["account"]), corev1.EventTypeNormal, reasonCreated, "LBServiceGroup %s %s", body.Group.Name, pastTense(reasonCreated))
+	http.Redirect(w, r, selfURL, http.StatusFound)
+}
+
+func (a *AccountAdmin) showGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+	group, err := db.ReadGroup(r.Context(), a.client, vars["account"], vars["group"])
+	if err != nil {
+		log.Error(err, "GET group failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	if group.Links, err = routeLinks(r, a.router, map[string]route{
+		"self":    {"admin-group", []string{"account", vars["account"], "group", vars["group"]}},
+		"account": {"admin-account", []string{"account", vars["account"]}},
+		"prefix":  {"admin-prefix", []string{"prefix", group.Group.Labels[epicv1.OwningServicePrefixLabel]}},
+	}); err != nil {
+		log.Error(err, "GET group failed")
+		util.RespondError(w, err)
+		return
+	}
+	log.Info("GET group OK")
+	respondObject(w, r, group)
+}
+
+// putGroup changes a group's service prefix and sharing. It responds
+// 409 "conflict" if the group has services or proxies.
+func (a *AccountAdmin) putGroup(w http.ResponseWriter, r *http.Request) {
+	var body model.GroupTemplate
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Error(err, "PUT group failed")
+		util.RespondBad(w, err)
+		return
+	}
+	body.Name = vars["group"]
+
+	if !a.checkPrefix(w, r, body.ServicePrefix) {
+		return
+	}
+
+	if err := db.UpdateGroup(r.Context(), a.client, vars["account"], body); err != nil {
+		log.Error(err, "PUT group failed")
+		switch {
+		case errors.IsNotFound(err):
+			util.RespondNotFound(w, err)
+		case err == db.ErrGroupInUse:
+			util.RespondProblem(w, http.StatusConflict, err.Error())
+		default:
+			util.RespondError(w, err)
+		}
+		return
+	}
+
+	selfURL, err := util.RouteURL(r, a.router, "admin-group", "account", vars["account"], "group", vars["group"])
+	if err != nil {
+		log.Error(err, "PUT group failed")
+		util.RespondError(w, err)
+		return
+	}
+	log.Info("PUT group OK", "group", body)
+	recordEvent(a.recorder, r, accountRef(r.Context(), a.client, vars["account"]), corev1.EventTypeNormal, reasonUpdated, "LBServiceGroup %s %s", vars["group"], pastTense(reasonUpdated))
+	http.Redirect(w, r, selfURL, http.StatusFound)
+}
+
+// delGroup deletes a group. It responds 409 "conflict" if the group
+// has services or proxies.
+func (a *AccountAdmin) delGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	if err := db.DeleteGroup(r.Context(), a.client, vars["account"], vars["group"]); err != nil {
+		log.Error(err, "DELETE group failed")
+		switch {
+		case errors.IsNotFound(err):
+			util.RespondNotFound(w, err)
+		case err == db.ErrGroupInUse:
+			util.RespondProblem(w, http.StatusConflict, err.Error())
+		default:
+			util.RespondError(w, err)
+		}
+		return
+	}
+
+	log.Info("DELETE group OK")
+	recordEvent(a.recorder, r, accountRef(r.Context(), a.client, vars["account"]), corev1.EventTypeNormal, reasonDeleted, "LBServiceGroup %s %s", vars["group"], pastTense(reasonDeleted))
+	util.RespondJSON(w, http.StatusOK, map[string]string{"message": "group deleted"}, util.EmptyHeader)
+}
+
+// checkPrefix checks that there's a ServicePrefix with the provided
+// name. If not it responds 400 "bad request" and returns false.
+func (a *AccountAdmin) checkPrefix(w http.ResponseWriter, r *http.Request, name string) bool {
+	log := log.FromContext(r.Context())
+
+	if name == "" {
+		util.RespondBad(w, fmt.Errorf("servicePrefix is required"))
+		return false
+	}
+	if _, err := db.ReadPrefix(r.Context(), a.client, name); err != nil {
+		log.Error(err, "reading service prefix failed", "prefix", name)
+		if errors.IsNotFound(err) {
+			util.RespondBad(w, fmt.Errorf("service prefix %q not found", name))
+			return false
+		}
+		util.RespondError(w, err)
+		return false
+	}
+	return true
+}
+
+// SetupAccountAdminRoutes sets up the provided mux.Router to handle
+// the account and group management routes. New accounts get defaultGroups
+// unless the request lists its own, and if clusterRole isn't empty
+// it's bound in each new account's namespace.
+func SetupAccountAdminRoutes(router *mux.Router, client client.Client, recorder record.EventRecorder, defaultGroups []model.GroupTemplate, clusterRole string) {
+	admin := &AccountAdmin{client: client, router: router, recorder: recorder, defaultGroups: defaultGroups, clusterRole: clusterRole}
+	router.HandleFunc("/accounts/{account}/groups/{group}", admin.showGroup).Methods(http.MethodGet).Name("admin-group")
+	router.HandleFunc("/accounts/{account}/groups/{group}", admin.putGroup).Methods(http.MethodPut)
+	router.HandleFunc("/accounts/{account}/groups/{group}", admin.delGroup).Methods(http.MethodDelete)
+	router.HandleFunc("/accounts/{account}/groups", admin.createGroup).Methods(http.MethodPost)
+	router.HandleFunc("/accounts/{account}", admin.show).Methods(http.MethodGet).Name("admin-account")
+	router.HandleFunc("/accounts/{account}", admin.del).Methods(http.MethodDelete)
+	router.HandleFunc("/accounts", admin.create).Methods(http.MethodPost)
+	router.HandleFunc("/accounts", admin.list).Methods(http.MethodGet).Name("admin-accounts")
+}
@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/pool"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+const (
+	// detailAddressOutsidePool is the detail code of responses to
+	// requests for an address that isn't in the group's pool.
+	detailAddressOutsidePool = "address-outside-pool"
+
+	// detailAddressInUse is the detail code of responses to requests
+	// for an address that something else holds.
+	detailAddressInUse = "address-in-use"
+
+	// detailUnknownReservation is the detail code of responses to
+	// requests that name a reservation that doesn't exist.
+	detailUnknownReservation = "unknown-reservation"
+)
+
+// AddressRequest is the part of a create request that asks for a
+// specific public address from the group's service prefix. Address
+// must be granted or the create fails; AddressHint is used if it's
+// available and otherwise the allocator picks as usual. Reservation
+// names a reservation whose address the new object should take; if
+// it's empty then the reservation that an earlier object with the
+// same identity left, if any, is used.
+type AddressRequest struct {
+	Address     string `json:"address,omitempty"`
+	AddressHint string `json:"address-hint,omitempty"`
+	Reservation string `json:"reservation,omitempty"`
+}
+
+// addressClaim identifies the object that a create request is for.
+type addressClaim struct {
+	account string
+	kind    string
+	name    string
+
+	// reservation is the name of the reservation that an earlier
+	// object with the same identity would have left.
+	reservation string
+}
+
+// requestAddress works out which public address to ask the allocator
+// for on behalf of claim's object. It returns "" to let the allocator
+// pick. If the address comes from a reservation then it also returns
+// the reservation's name so the caller can release it once the
+// object exists. If the request can't be met it sends the response
+// and returns false.
+func requestAddress(ctx context.Context, w http.ResponseWriter, cl client.Client, prefixName string, req AddressRequest, claim addressClaim) (string, string, bool) {
+	log := log.FromContext(ctx)
+
+	if req.Address != "" && req.AddressHint != "" {
+		util.RespondBad(w, fmt.Errorf("address and address-hint are mutually exclusive"))
+		return "", "", false
+	}
+
+	// Find the reservation, if any.
+	explicit := req.Reservation != ""
+	reservationName := req.Reservation
+	if !explicit {
+		reservationName = claim.reservation
+	}
+	var reservation *model.Reservation
+	if reservationName != "" {
+		var err error
+		reservation, err = db.ReadReservation(ctx, cl, claim.account, reservationName)
+		switch {
+		case errors.IsNotFound(err):
+			if explicit {
+				util.RespondDetail(w, http.StatusUnprocessableEntity, detailUnknownReservation,
+					fmt.Sprintf("reservation %s not found", reservationName), util.EmptyHeader)
+				return "", "", false
+			}
+			reservation = nil
+		case err != nil:
+			log.Error(err, "reading reservation failed", "reservation", reservationName)
+			util.RespondError(w, err)
+			return "", "", false
+		case reservation.Prefix != prefixName:
+			if explicit {
+				util.RespondDetail(w, http.StatusUnprocessableEntity, detailAddressOutsidePool,
+					fmt.Sprintf("reservation %s is for service prefix %s, not %s", reservationName, reservation.Prefix, prefixName), util.EmptyHeader)
+				return "", "", false
+			}
+			reservation = nil
+		}
+	}
+
+	required := req.Address != ""
+	want := req.Address
+	if !required {
+		want = req.AddressHint
+	}
+	if want == "" && reservation != nil {
+		want = reservation.Address
+		required = explicit
+	}
+
+	prefix, err := db.ReadPrefix(ctx, cl, prefixName)
+	if err != nil {
+		log.Error(err, "reading service prefix failed", "prefix", prefixName)
+		util.RespondError(w, err)
+		return "", "", false
+	}
+	addressPool, err := pool.Parse(prefix.Spec.Pool)
+	if err != nil {
+		log.Error(err, "invalid service prefix pool", "prefix", prefixName)
+		util.RespondError(w, err)
+		return "", "", false
+	}
+	holders, err := db.ListPrefixHolders(ctx, cl, prefix)
+	if err != nil {
+		log.Error(err, "listing address holders failed", "prefix", prefixName)
+		util.RespondError(w, err)
+		return "", "", false
+	}
+
+	// Holders other than the object itself, its reservation, and the
+	// object that left the reservation, which might still be on its
+	// way out.
+	namespace := epicv1.AccountNamespace(claim.account)
+	used := map[string]struct{}{}
+	reserved := false
+	for _, holder := range holders {
+		if holder.Namespace == namespace && isClaimant(holder, claim, reservation) {
+			continue
+		}
+		if ip := net.ParseIP(holder.Address); ip != nil {
+			used[ip.String()] = struct{}{}
+		}
+		reserved = reserved || holder.Kind == db.ReservationKind || holder.Picked
+	}
+
+	if want != "" {
+		ip := net.ParseIP(want)
+		problem := ""
+		switch {
+		case ip == nil:
+			if required {
+				util.RespondBad(w, fmt.Errorf("invalid address %q", want))
+				return "", "", false
+			}
+			problem = "invalid"
+		case !addressPool.Contains(ip):
+			if required {
+				util.RespondDetail(w, http.StatusUnprocessableEntity, detailAddressOutsidePool,
+					fmt.Sprintf("address %s is not in service prefix %s (%s)", ip, prefixName, prefix.Spec.Pool), util.EmptyHeader)
+				return "", "", false
+			}
+			problem = "outside pool"
+		default:
+			// A duplicate create isn't a conflict over the address since
+			// we skipped the object's own holder; the create will report
+			// it.
+			if _, taken := used[ip.String()]; taken {
+				if required {
+					util.RespondDetail(w, http.StatusConflict, detailAddressInUse,
+						fmt.Sprintf("address %s is in use", ip), util.EmptyHeader)
+					return "", "", false
+				}
+				problem = "in use"
+			}
+		}
+
+		if problem == "" {
+			if reservation != nil && ip.Equal(net.ParseIP(reservation.Address)) {
+				return ip.String(), reservation.Name, true
+			}
+			return ip.String(), "", true
+		}
+		log.Info("requested address unavailable, ignoring", "address", want, "problem", problem, "prefix", prefixName)
+	}
+
+	// The allocator doesn't know about reservations, or addresses that
+	// we picked for objects that it hasn't seen yet, so if there are
+	// any then we have to pick the address ourselves.
+	if reserved {
+		ip, err := db.PickAddress(ctx, cl, prefix, addressPool, used, claim.account, claim.kind, claim.name)
+		if err != nil {
+			log.Error(err, "picking address failed", "prefix", prefixName)
+			util.RespondError(w, err)
+			return "", "", false
+		}
+		if ip != nil {
+			return ip.String(), "", true
+		}
+	}
+
+	return "", "", true
+}
+
+// isClaimant indicates whether holder is claim's object, its
+// reservation, or the object that left the reservation.
+func isClaimant(holder db.AddressHolder, claim addressClaim, reservation *model.Reservation) bool {
+	if holder.Kind == claim.kind && holder.Name == claim.name {
+		return true
+	}
+	if reservation == nil {
+		return false
+	}
+	return holder.Kind == db.ReservationKind && holder.Name == reservation.Name ||
+		holder.Kind == reservation.Kind && holder.Name == reservation.Object
+}
+
+// respondIfAddressTaken checks whether a create that asked for
+// address failed because something else got the address after
+// requestAddress checked it. If so it sends a 409 "conflict" response
+// and returns true, otherwise it does nothing and returns false.
+//
+// We don't try to read the allocator's message. If EPIC's webhook
+// denied the create then we look at the prefix's holders again, and
+// if someone other than claim's object or its reservation has the
+// address now then that's why.
+func respondIfAddressTaken(ctx context.Context, w http.ResponseWriter, cl client.Client, prefixName string, address string, reservation string, claim addressClaim, createErr error) bool {
+	if address == "" || !admissionDenied(createErr) {
+		return false
+	}
+
+	prefix, err := db.ReadPrefix(ctx, cl, prefixName)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "reading service prefix failed", "prefix", prefixName)
+		return false
+	}
+	holders, err := db.ListPrefixHolders(ctx, cl, prefix)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "listing address holders failed", "prefix", prefixName)
+		return false
+	}
+	namespace := epicv1.AccountNamespace(claim.account)
+	taken := false
+	for _, holder := range holders {
+		if holder.Namespace == namespace && (holder.Kind == claim.kind && holder.Name == claim.name ||
+			holder.Kind == db.ReservationKind && holder.Name == reservation) {
+			continue
+		}
+		if ip := net.ParseIP(holder.Address); ip != nil && ip.Equal(net.ParseIP(address)) {
+			taken = true
+			break
+		}
+	}
+	if !taken {
+		return false
+	}
+
+	util.RespondDetail(w, http.StatusConflict, detailAddressInUse,
+		fmt.Sprintf("address %s is in use: %s", address, createErr), util.EmptyHeader)
+	return true
+}
@@ -0,0 +1,324 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+)
+
+// Related objects that clients can ask to have embedded in a
+// response with the "embed" query parameter, e.g.,
+// "?embed=routes,slices".
+const (
+	embedGroup     = "group"
+	embedProxies   = "proxies"
+	embedRoutes    = "routes"
+	embedSlices    = "slices"
+	embedEndpoints = "endpoints"
+)
+
+// embedParam parses the request's "embed" query parameter. It
+// returns an error if the client asked for a relation that isn't in
+// allowed.
+func embedParam(r *http.Request, allowed ...string) (map[string]bool, error) {
+	embeds := map[string]bool{}
+	for _, value := range r.URL.Query()["embed"] {
+		for _, rel := range strings.Split(value, ",") {
+			if rel = strings.TrimSpace(rel); rel == "" {
+				continue
+			}
+			if !contains(allowed, rel) {
+				return nil, fmt.Errorf("can't embed %q, expected one of [%s]", rel, strings.Join(allowed, ","))
+			}
+			embeds[rel] = true
+		}
+	}
+	return embeds, nil
+}
+
+// embedder finds the objects that are related to an object, and
+// prepares them for embedding: with links, and redacted.
+//
+// Relationships between objects:
+//   - LoadBalancers and GWProxies belong to the LBServiceGroup in
+//     their OwningLBServiceGroupLabel.
+//   - GWRoutes attach to the GWProxies named in their parentRefs.
+//   - GWRoutes send traffic to the GWEndpointSlices named in their
+//     backendRefs.
+//   - RemoteEndpoints belong to the LoadBalancer in their
+//     OwningLoadBalancerLabel.
+type embedder struct {
+	client  client.Client
+	router  *mux.Router
+	r       *http.Request
+	account string
+}
+
+func newEmbedder(cl client.Client, router *mux.Router, r *http.Request) embedder {
+	return embedder{client: cl, router: router, r: r, account: mux.Vars(r)["account"]}
+}
+
+// forProxy returns the objects related to a GWProxy that the client
+// asked for.
+func (e embedder) forProxy(proxy *model.Proxy, embeds map[string]bool) (_ *model.Embedded, err error) {
+	embedded := &model.Embedded{}
+	if embeds[embedGroup] {
+		if embedded.Group, err = e.group(proxy.Proxy.Labels[epicv1.OwningLBServiceGroupLabel]); err != nil {
+			return nil, err
+		}
+	}
+	if embeds[embedRoutes] || embeds[embedSlices] {
+		routes, err := e.proxyRoutes(proxy.Proxy.Name)
+		if err != nil {
+			return nil, err
+		}
+		if embeds[embedSlices] {
+			if embedded.Slices, err = e.routeSlices(routes); err != nil {
+				return nil, err
+			}
+		}
+		if embeds[embedRoutes] {
+			embedded.Routes = routes
+		}
+	}
+	return embedded, nil
+}
+
+// forRoute returns the objects related to a GWRoute that the client
+// asked for.
+func (e embedder) forRoute(rt *model.Route, embeds map[string]bool) (_ *model.Embedded, err error) {
+	embedded := &model.Embedded{}
+	if embeds[embedProxies] {
+		if embedded.Proxies, err = e.routeProxies(&rt.Route); err != nil {
+			return nil, err
+		}
+	}
+	if embeds[embedSlices] {
+		if embedded.Slices, err = e.routeSlices([]model.Route{*rt}); err != nil {
+			return nil, err
+		}
+	}
+	return embedded, nil
+}
+
+// forSlice returns the objects related to a GWEndpointSlice that the
+// client asked for.
+func (e embedder) forSlice(slice *model.Slice, embeds map[string]bool) (_ *model.Embedded, err error) {
+	embedded := &model.Embedded{}
+	if embeds[embedRoutes] {
+		if embedded.Routes, err = e.sliceRoutes(slice.Slice.Name); err != nil {
+			return nil, err
+		}
+	}
+	return embedded, nil
+}
+
+// forService returns the objects related to a LoadBalancer that the
+// client asked for.
+func (e embedder) forService(service *model.Service, embeds map[string]bool) (_ *model.Embedded, err error) {
+	embedded := &model.Embedded{}
+	if embeds[embedGroup] {
+		if embedded.Group, err = e.group(service.Service.Labels[epicv1.OwningLBServiceGroupLabel]); err != nil {
+			return nil, err
+		}
+	}
+	if embeds[embedEndpoints] {
+		if embedded.Endpoints, err = e.serviceEndpoints(service.Service.Name); err != nil {
+			return nil, err
+		}
+	}
+	return embedded, nil
+}
+
+// group returns the named LBServiceGroup.
+func (e embedder) group(name string) (*model.Group, error) {
+	group, err := db.ReadGroup(e.r.Context(), e.client, e.account, name)
+	if err != nil {
+		return nil, err
+	}
+	if group.Links, err = routeLinks(e.r, e.router, map[string]route{
+		"self": {"group", []string{"account", e.account, "group", name}},
+	}); err != nil {
+		return nil, err
+	}
+	model.Redact(group)
+	return group, nil
+}
+
+// proxyRoutes returns the GWRoutes that are attached to the named
+// GWProxy.
+func (e embedder) proxyRoutes(proxyName string) ([]model.Route, error) {
+	return e.routes(func(rt *epicv1.GWRoute) bool {
+		return contains(routeParents(rt), proxyName)
+	})
+}
+
+// sliceRoutes returns the GWRoutes that send traffic to the named
+// GWEndpointSlice.
+func (e embedder) sliceRoutes(sliceName string) ([]model.Route, error) {
+	return e.routes(func(rt *epicv1.GWRoute) bool {
+		return contains(routeBackends(rt), sliceName)
+	})
+}
+
+func (e embedder) routes(match func(*epicv1.GWRoute) bool) ([]model.Route, error) {
+	all, err := db.ListRoutes(e.r.Context(), e.client, e.account)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []model.Route{}
+	for _, rt := range all {
+		if !match(&rt.Route) {
+			continue
+		}
+		if rt.Links, err = routeLinks(e.r, e.router, map[string]route{
+			"self": {"route", []string{"account", e.account, "route", rt.Route.Name}},
+		}); err != nil {
+			return nil, err
+		}
+		model.Redact(&rt)
+		routes = append(routes, rt)
+	}
+	return routes, nil
+}
+
+// routeSlices returns the GWEndpointSlices that routes send traffic
+// to. Each slice is included once even if several routes use it.
+// Slices that don't exist (yet) are skipped.
+func (e embedder) routeSlices(routes []model.Route) ([]model.Slice, error) {
+	wanted := map[string]bool{}
+	for _, rt := range routes {
+		for _, name := range routeBackends(&rt.Route) {
+			wanted[name] = true
+		}
+	}
+	if len(wanted) == 0 {
+		return []model.Slice{}, nil
+	}
+
+	all, err := db.ListSlices(e.r.Context(), e.client, e.account)
+	if err != nil {
+		return nil, err
+	}
+
+	slices := []model.Slice{}
+	for _, slice := range all {
+		if !wanted[slice.Slice.Name] {
+			continue
+		}
+		if slice.Links, err = routeLinks(e.r, e.router, map[string]route{
+			"self": {"slice", []string{"account", e.account, "slice", slice.Slice.Name}},
+		}); err != nil {
+			return nil, err
+		}
+		model.Redact(&slice)
+		slices = append(slices, slice)
+	}
+	return slices, nil
+}
+
+// routeProxies returns the GWProxies that a route is attached to.
+func (e embedder) routeProxies(rt *epicv1.GWRoute) ([]model.Proxy, error) {
+	parents := routeParents(rt)
+	if len(parents) == 0 {
+		return []model.Proxy{}, nil
+	}
+
+	all, err := db.ListProxies(e.r.Context(), e.client, e.account)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies := []model.Proxy{}
+	for _, proxy := range all {
+		if !contains(parents, proxy.Proxy.Name) {
+			continue
+		}
+		if proxy.Links, err = routeLinks(e.r, e.router, map[string]route{
+			"self": {"proxy", []string{"account", e.account, "proxy", proxy.Proxy.Name}},
+		}); err != nil {
+			return nil, err
+		}
+		model.Redact(&proxy)
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// serviceEndpoints returns the RemoteEndpoints that belong to the
+// named LoadBalancer.
+func (e embedder) serviceEndpoints(serviceName string) ([]model.Endpoint, error) {
+	all, err := db.ListEndpoints(e.r.Context(), e.client, e.account, client.MatchingLabels{epicv1.OwningLoadBalancerLabel: serviceName})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []model.Endpoint{}
+	for _, ep := range all {
+		if ep.Links, err = routeLinks(e.r, e.router, map[string]route{
+			"self": {"endpoint", []string{"account", e.account, "service", serviceName, "endpoint", ep.Endpoint.Name}},
+		}); err != nil {
+			return nil, err
+		}
+		model.Redact(&ep)
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, nil
+}
+
+// routeHostnames returns the hostnames that a route serves.
+func routeHostnames(rt *epicv1.GWRoute) []string {
+	names := []string{}
+	if rt.Spec.HTTP == nil {
+		return names
+	}
+	for _, hostname := range rt.Spec.HTTP.Hostnames {
+		names = append(names, string(hostname))
+	}
+	return names
+}
+
+// routeParents returns the names of the GWProxies that a route is
+// attached to.
+func routeParents(rt *epicv1.GWRoute) []string {
+	names := []string{}
+	if rt.Spec.HTTP == nil {
+		return names
+	}
+	for _, parent := range rt.Spec.HTTP.ParentRefs {
+		names = append(names, string(parent.Name))
+	}
+	return names
+}
+
+// routeBackends returns the names of the GWEndpointSlices that a
+// route sends traffic to.
+func routeBackends(rt *epicv1.GWRoute) []string {
+	names := []string{}
+	if rt.Spec.HTTP == nil {
+		return names
+	}
+	for _, rule := range rt.Spec.HTTP.Rules {
+		for _, backend := range rule.BackendRefs {
+			names = append(names, string(backend.Name))
+		}
+	}
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -10,8 +10,12 @@ import (
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
 	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"acnodal.io/epic/web-service/internal/db"
 	"acnodal.io/epic/web-service/internal/model"
@@ -26,14 +30,16 @@ var (
 
 // EPIC implements the server side of the EPIC web service protocol.
 type EPIC struct {
-	client client.Client
-	router *mux.Router
+	client   client.Client
+	router   *mux.Router
+	recorder record.EventRecorder
 }
 
 // ServiceCreateRequest contains the data from a web service request
 // to create a Service.
 type ServiceCreateRequest struct {
 	Service epicv1.LoadBalancer
+	AddressRequest
 }
 
 // ClusterCreateRequest contains the data from a web service request
@@ -57,10 +63,11 @@ func (g *EPIC) createService(w http.ResponseWriter, r *http.Request) {
 		body ServiceCreateRequest
 	)
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
-		fmt.Printf("POST service failed %#v\n", err)
+		log.Error(err, "POST service failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -69,7 +76,7 @@ func (g *EPIC) createService(w http.ResponseWriter, r *http.Request) {
 	// which we'll allocate the address
 	group, err := db.ReadGroup(r.Context(), g.client, vars["account"], vars["group"])
 	if err != nil {
-		fmt.Printf("POST service failed %#v\n", err)
+		log.Error(err, "POST service failed")
 		util.RespondNotFound(w, err)
 		return
 	}
@@ -86,83 +93,173 @@ func (g *EPIC) createService(w http.ResponseWriter, r *http.Request) {
 	// This LB will live in the same NS as its owning group
 	body.Service.Namespace = group.Group.Namespace
 
-	selfURL, err := g.router.Get("service").URL("account", vars["account"], "service", body.Service.ObjectMeta.Name)
+	selfURL, err := util.RouteURL(r, g.router, "service", "account", vars["account"], "service", body.Service.ObjectMeta.Name)
 	if err != nil {
-		fmt.Printf("POST service failed %s/%s: %s\n", vars["account"], vars["group"], err)
+		log.Error(err, "POST service failed")
 		util.RespondError(w, err)
 		return
 	}
 
+	// If the client asked for an address, or has a reservation, then
+	// check that it can have it
+	claim := addressClaim{account: vars["account"], kind: "LoadBalancer", name: body.Service.Name, reservation: db.ServiceReservationName(body.Service.Name)}
+	address, reservation, ok := requestAddress(r.Context(), w, g.client, body.Service.Labels[epicv1.OwningServicePrefixLabel], body.AddressRequest, claim)
+	if !ok {
+		log.Info("POST service failed, address unavailable", "address", body.Address, "hint", body.AddressHint, "reservation", body.Reservation)
+		return
+	}
+	if address != "" {
+		body.Service.Spec.PublicAddress = address
+	}
+
 	// Create the LB CR
 	err = g.client.Create(r.Context(), &body.Service)
 	if err != nil {
 		matches := duplicateLB.FindStringSubmatch(err.Error())
 		if len(matches) > 0 {
-			fmt.Printf("POST service 409/duplicate %s/%s\n", vars["account"], body.Service.Name)
+			log.Info("POST service 409/duplicate")
+			recordConflict(g.recorder, g.client, r, &body.Service, "LoadBalancer", body.Service.Name)
 
 			// We already had that endpoint, but we can return what we hope
 			// the client needs to set up the tunnels on its end
 			util.RespondConflict(
 				w,
-				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL.String()}},
-				map[string]string{"Location": selfURL.String()},
+				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL}},
+				map[string]string{"Location": selfURL},
 			)
 			return
 		}
 
+		// The address that the client asked for might have been taken
+		if respondIfAddressTaken(r.Context(), w, g.client, body.Service.Labels[epicv1.OwningServicePrefixLabel], address, reservation, claim, err) {
+			log.Info("POST service 409/address in use", "address", address)
+			return
+		}
+
+		// The allocator might have run out of addresses
+		if respondIfExhausted(r.Context(), w, g.client, body.Service.Labels[epicv1.OwningServicePrefixLabel], address, err) {
+			log.Error(err, "POST service failed, pool exhausted")
+			return
+		}
+
 		// Something else went wrong
-		fmt.Printf("POST service failed %#v\n", err)
+		log.Error(err, "POST service failed")
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("POST service OK %v %#v\n", vars["account"], body.Service.Spec)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	// The new service has the reserved address so the reservation isn't
+	// needed
+	if reservation != "" {
+		releaseClaimed(r.Context(), g.client, vars["account"], reservation)
+	}
+
+	log.Info("POST service OK", "spec", body.Service.Spec)
+	recordNormal(g.recorder, g.client, r, vars["account"], &body.Service, reasonCreated, "LoadBalancer", body.Service.Name)
+	http.Redirect(w, r, selfURL, http.StatusFound)
 }
 
 func (g *EPIC) showService(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	service, err := db.ReadService(r.Context(), g.client, vars["account"], vars["service"])
 	if err == nil {
-		groupLink, err := g.router.Get("group").URL("account", vars["account"], "group", service.Service.Labels[epicv1.OwningLBServiceGroupLabel])
+		links, err := routeLinks(r, g.router, map[string]route{
+			"self":            {"service", []string{"account", vars["account"], "service", vars["service"]}},
+			"group":           {"group", []string{"account", vars["account"], "group", service.Service.Labels[epicv1.OwningLBServiceGroupLabel]}},
+			"create-endpoint": {"service-endpoints", []string{"account", vars["account"], "service", vars["service"]}},
+			"create-cluster":  {"service-clusters", []string{"account", vars["account"], "service", vars["service"]}},
+		})
 		if err != nil {
-			fmt.Printf("GET service failed %s/%s: %s\n", vars["account"], vars["group"], err)
+			log.Error(err, "GET service failed")
 			util.RespondError(w, err)
 			return
 		}
-		service.Links = model.Links{
-			"self":            fmt.Sprintf("%s", r.RequestURI),
-			"group":           groupLink.String(),
-			"create-endpoint": fmt.Sprintf("%s/endpoints", r.RequestURI),
-			"create-cluster":  fmt.Sprintf("%s/clusters", r.RequestURI),
+		service.Links = links
+		embeds, err := embedParam(r, embedGroup, embedEndpoints)
+		if err != nil {
+			log.Error(err, "GET service failed")
+			util.RespondBad(w, err)
+			return
+		}
+		if len(embeds) > 0 {
+			if service.Embedded, err = newEmbedder(g.client, g.router, r).forService(service, embeds); err != nil {
+				log.Error(err, "GET service failed")
+				util.RespondError(w, err)
+				return
+			}
 		}
-		fmt.Printf("GET service OK %s/%s\n", vars["account"], vars["service"])
-		util.RespondJSON(w, http.StatusOK, service, util.EmptyHeader)
+		log.Info("GET service OK")
+		respondObject(w, r, service)
 		return
 	}
-	fmt.Printf("GET service failed %s/%s %#v\n", vars["account"], vars["service"], err)
+	log.Error(err, "GET service failed")
 	util.RespondNotFound(w, err)
 }
 
 func (g *EPIC) deleteService(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	hold, err := parseHold(r)
+	if err != nil {
+		log.Error(err, "DELETE service failed")
+		util.RespondBad(w, err)
+		return
+	}
+
+	// Read the service first so we know which address to hold
+	service, err := db.ReadService(r.Context(), g.client, vars["account"], vars["service"])
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "DELETE service failed")
+		util.RespondError(w, err)
+		return
+	}
+	found := err == nil
+
+	// Hold the address before the delete so a create can't take it
+	// in between
+	var reservation model.Reservation
+	held := false
+	if found && hold.hold {
+		reservation = serviceReservation(&service.Service, hold.name)
+		if held, err = holdAddress(r.Context(), g.client, vars["account"], reservation); err != nil {
+			log.Error(err, "DELETE service failed")
+			util.RespondError(w, err)
+			return
+		}
+	}
 
 	// Delete the CR
 	if err := db.DeleteService(r.Context(), g.client, vars["account"], vars["service"]); err != nil {
+		if held {
+			releaseClaimed(r.Context(), g.client, vars["account"], reservation.Name)
+		}
+
 		matches := multiClusterLB.FindStringSubmatch(err.Error())
 		if len(matches) > 0 {
-			fmt.Printf("service %s has clusters: %s\n", vars["service"], err)
+			log.Info("service has clusters", "error", err.Error())
 			util.RespondConflict(w, map[string]interface{}{"message": err.Error()}, util.EmptyHeader)
 			return
 		}
 
-		fmt.Printf("DELETE service failed %s/%s %#v\n", vars["account"], vars["service"], err)
+		log.Error(err, "DELETE service failed")
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("DELETE service OK %s/%s\n", vars["account"], vars["service"])
-	util.RespondJSON(w, http.StatusOK, map[string]string{"message": "delete successful"}, map[string]string{})
+	response := map[string]string{"message": "delete successful"}
+	if held {
+		if link := reservationLink(r, g.router, vars["account"], reservation.Name); link != "" {
+			response["reservation"] = link
+		}
+	}
+
+	log.Info("DELETE service OK")
+	if found {
+		recordNormal(g.recorder, g.client, r, vars["account"], &service.Service, reasonDeleted, "LoadBalancer", vars["service"])
+	}
+	util.RespondJSON(w, http.StatusOK, response, map[string]string{})
 	return
 }
 
@@ -174,10 +271,11 @@ func (g *EPIC) createServiceCluster(w http.ResponseWriter, r *http.Request) {
 		patchBytes []byte
 	)
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
-		fmt.Printf("POST cluster failed %#v\n", err)
+		log.Error(err, "POST cluster failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -185,34 +283,35 @@ func (g *EPIC) createServiceCluster(w http.ResponseWriter, r *http.Request) {
 	// Validate the client cluster ID
 	if body.ClusterID == "" {
 		err := fmt.Errorf("cluster name not provided")
-		fmt.Printf("POST cluster failed %#v\n", err)
+		log.Error(err, "POST cluster failed")
 		util.RespondBad(w, err)
 		return
 	}
 
 	// Calculate our "self" URL
-	selfURL, err := g.router.Get("cluster").URL("account", vars["account"], "service", vars["service"], "cluster", url.QueryEscape(body.ClusterID))
+	selfURL, err := util.RouteURL(r, g.router, "cluster", "account", vars["account"], "service", vars["service"], "cluster", url.QueryEscape(body.ClusterID))
 	if err != nil {
-		fmt.Printf("GET cluster failed %s/%s/%s: %s\n", vars["account"], vars["service"], body.ClusterID, err)
+		log.Error(err, "POST cluster failed")
 		util.RespondError(w, err)
 		return
 	}
 
 	service, err = db.ReadService(r.Context(), g.client, vars["account"], vars["service"])
 	if err != nil {
-		fmt.Printf("POST cluster failed %s/%s/%s %#v\n", vars["account"], vars["service"], body.ClusterID, err)
+		log.Error(err, "POST cluster failed")
 		util.RespondNotFound(w, err)
 	}
 
 	// Check if the LB already has this cluster and error if it does
 	if err := service.Service.AddUpstream(body.ClusterID); err != nil {
-		fmt.Printf("Duplicate cluster %#v: %s\n", body.ClusterID, err)
+		log.Info("duplicate cluster", "cluster", body.ClusterID, "error", err.Error())
+		recordEvent(g.recorder, r, &service.Service, corev1.EventTypeWarning, reasonConflict, "duplicate upstream cluster %s rejected", body.ClusterID)
 
 		// The LB already had that cluster
 		util.RespondConflict(
 			w,
-			map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL.String()}},
-			map[string]string{"Location": selfURL.String()},
+			map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL}},
+			map[string]string{"Location": selfURL},
 		)
 		return
 	}
@@ -241,26 +340,26 @@ func (g *EPIC) createServiceCluster(w http.ResponseWriter, r *http.Request) {
 
 	// apply the patch
 	if patchBytes, err = json.Marshal(patch); err != nil {
-		fmt.Printf("POST cluster failed %#v\n", err)
+		log.Error(err, "POST cluster failed")
 		util.RespondError(w, err)
 		return
 	}
 	if err = g.client.Patch(r.Context(), &service.Service, client.RawPatch(types.JSONPatchType, patchBytes)); err != nil {
-		fmt.Println(string(patchBytes))
-		fmt.Printf("POST cluster failed %#v\n", err)
+		log.Error(err, "POST cluster failed", "patch", string(patchBytes))
 		util.RespondError(w, err)
 		return
 	}
 
 	if err != nil {
 		// Something went wrong
-		fmt.Printf("POST cluster failed %#v\n", err)
+		log.Error(err, "POST cluster failed")
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("POST cluster OK %s/%s %s\n", vars["account"], vars["service"], body.ClusterID)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	log.Info("POST cluster OK", "cluster", body.ClusterID)
+	recordEvent(g.recorder, r, &service.Service, corev1.EventTypeNormal, reasonUpdated, "upstream cluster %s added", body.ClusterID)
+	http.Redirect(w, r, selfURL, http.StatusFound)
 }
 
 func (g *EPIC) showCluster(w http.ResponseWriter, r *http.Request) {
@@ -269,10 +368,11 @@ func (g *EPIC) showCluster(w http.ResponseWriter, r *http.Request) {
 		service *model.Service
 	)
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	cluster, err := url.QueryUnescape(vars["cluster"])
 	if err != nil {
-		fmt.Printf("GET cluster failed %s/%s %s %#v\n", vars["account"], vars["service"], cluster, err)
+		log.Error(err, "GET cluster failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -280,7 +380,7 @@ func (g *EPIC) showCluster(w http.ResponseWriter, r *http.Request) {
 	// 404 if we can't find the service
 	service, err = db.ReadService(r.Context(), g.client, vars["account"], vars["service"])
 	if err != nil {
-		fmt.Printf("GET cluster failed %s/%s %s %#v\n", vars["account"], vars["service"], cluster, err)
+		log.Error(err, "GET cluster failed")
 		util.RespondNotFound(w, err)
 		return
 	}
@@ -288,20 +388,22 @@ func (g *EPIC) showCluster(w http.ResponseWriter, r *http.Request) {
 	// 404 if the service doesn't have a cluster with that name
 	if !service.Service.ContainsUpstream(cluster) {
 		err = fmt.Errorf("cluster %s/%s %s not found", vars["account"], vars["service"], cluster)
-		fmt.Printf("GET cluster failed %#v\n", err)
+		log.Error(err, "GET cluster failed")
 		util.RespondNotFound(w, err)
 		return
 	}
 
-	srvLink, err := g.router.Get("service").URL("account", vars["account"], "service", vars["service"])
+	links, err := routeLinks(r, g.router, map[string]route{
+		"self":    {"cluster", []string{"account", vars["account"], "service", vars["service"], "cluster", vars["cluster"]}},
+		"service": {"service", []string{"account", vars["account"], "service", vars["service"]}},
+	})
 	if err != nil {
-		fmt.Printf("GET group failed %s/%s: %s\n", vars["account"], vars["group"], err)
+		log.Error(err, "GET cluster failed")
 		util.RespondError(w, err)
 		return
 	}
-	links := model.Links{"self": r.RequestURI, "service": srvLink.String()}
 
-	fmt.Printf("GET cluster OK %s/%s %s\n", vars["account"], vars["service"], cluster)
+	log.Info("GET cluster OK")
 	util.RespondJSON(w, http.StatusOK, model.Cluster{Links: links}, util.EmptyHeader)
 	return
 }
@@ -311,30 +413,41 @@ func (g *EPIC) deleteCluster(w http.ResponseWriter, r *http.Request) {
 		err error
 	)
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	cluster, err := url.QueryUnescape(vars["cluster"])
 	if err != nil {
-		fmt.Printf("DELETE cluster failed %s/%s %s %#v\n", vars["account"], vars["service"], cluster, err)
+		log.Error(err, "DELETE cluster failed")
 		util.RespondBad(w, err)
 	}
 
+	// Read the service first so the event can refer to it
+	service, err := db.ReadService(r.Context(), g.client, vars["account"], vars["service"])
+	if err != nil {
+		log.Error(err, "DELETE cluster failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
 	if err = db.DeleteCluster(r.Context(), g.client, vars["account"], vars["service"], cluster); err != nil {
-		fmt.Printf("DELETE cluster failed %s/%s %s %#v\n", vars["account"], vars["service"], cluster, err)
+		log.Error(err, "DELETE cluster failed")
 		util.RespondError(w, err)
 	}
 
 	if err = db.DeleteClusterReps(r.Context(), g.client, vars["account"], vars["service"], cluster); err != nil {
-		fmt.Printf("DELETE cluster failed %s/%s %s %#v\n", vars["account"], vars["service"], cluster, err)
+		log.Error(err, "DELETE cluster failed")
 		util.RespondError(w, err)
 	}
 
-	fmt.Printf("DELETE cluster OK %s/%s %s\n", vars["account"], vars["service"], cluster)
+	log.Info("DELETE cluster OK")
+	recordEvent(g.recorder, r, &service.Service, corev1.EventTypeNormal, reasonUpdated, "upstream cluster %s removed", cluster)
 	util.RespondJSON(w, http.StatusOK, map[string]string{"message": "delete successful"}, map[string]string{})
 	return
 }
 
 func (g *EPIC) createServiceEndpoint(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	var (
 		body    EndpointCreateRequest
 		err     error
@@ -344,6 +457,7 @@ func (g *EPIC) createServiceEndpoint(w http.ResponseWriter, r *http.Request) {
 	// Parse request
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
+		log.Error(err, "POST endpoint failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -351,6 +465,7 @@ func (g *EPIC) createServiceEndpoint(w http.ResponseWriter, r *http.Request) {
 	// Read the service to which this endpoint will belong
 	service, err = db.ReadService(r.Context(), g.client, vars["account"], vars["service"])
 	if err != nil {
+		log.Error(err, "POST endpoint failed")
 		util.RespondNotFound(w, err)
 		return
 	}
@@ -385,8 +500,13 @@ func (g *EPIC) createServiceEndpoint(w http.ResponseWriter, r *http.Request) {
 
 			// We already had that endpoint, but we can return what we hope
 			// the client needs to set up the tunnels on its end
-			otherURL := fmt.Sprintf("%s/%s", r.RequestURI, matches[1])
-			fmt.Printf("POST endpoint 409/duplicate %s\n", body.Endpoint.Name)
+			otherURL, err := util.RouteURL(r, g.router, "endpoint", "account", vars["account"], "service", vars["service"], "endpoint", matches[1])
+			if err != nil {
+				log.Error(err, "POST endpoint failed", "endpoint", matches[1])
+				util.RespondError(w, err)
+				return
+			}
+			log.Info("POST endpoint 409/duplicate", "endpoint", body.Endpoint.Name)
 			util.RespondConflict(
 				w,
 				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": otherURL}, "endpoint": body.Endpoint},
@@ -396,138 +516,228 @@ func (g *EPIC) createServiceEndpoint(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Something else went wrong
-		fmt.Printf("POST endpoint failed %#v %#v\n", body, err)
+		log.Error(err, "POST endpoint failed", "spec", body.Endpoint.Spec)
 		util.RespondError(w, err)
 		return
 	}
 
-	selfURL, err := g.router.Get("endpoint").URL("account", vars["account"], "service", vars["service"], "endpoint", body.Endpoint.Name)
+	selfURL, err := util.RouteURL(r, g.router, "endpoint", "account", vars["account"], "service", vars["service"], "endpoint", body.Endpoint.Name)
 	if err != nil {
-		fmt.Printf("POST endpoint failed %s/%s/%s: %s\n", vars["account"], vars["service"], body.Endpoint.Name, err)
+		log.Error(err, "POST endpoint failed", "endpoint", body.Endpoint.Name)
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("POST endpoint OK %#v\n", body.Endpoint.Spec)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	log.Info("POST endpoint OK", "spec", body.Endpoint.Spec)
+	http.Redirect(w, r, selfURL, http.StatusFound)
 	return
 }
 
 func (g *EPIC) showEndpoint(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	ep, err := db.ReadEndpoint(r.Context(), g.client, vars["account"], vars["endpoint"])
 	if err == nil {
-		srvLink, err := g.router.Get("service").URL("account", vars["account"], "service", vars["service"])
+		links, err := routeLinks(r, g.router, map[string]route{
+			"self":    {"endpoint", []string{"account", vars["account"], "service", vars["service"], "endpoint", vars["endpoint"]}},
+			"service": {"service", []string{"account", vars["account"], "service", vars["service"]}},
+		})
 		if err != nil {
-			fmt.Printf("GET group failed %s/%s: %s\n", vars["account"], vars["group"], err)
+			log.Error(err, "GET endpoint failed")
 			util.RespondError(w, err)
 			return
 		}
-		ep.Links = model.Links{"self": r.RequestURI, "service": srvLink.String()}
+		ep.Links = links
 
-		fmt.Printf("GET endpoint OK %s/%s\n", vars["account"], vars["endpoint"])
-		util.RespondJSON(w, http.StatusOK, ep, util.EmptyHeader)
+		log.Info("GET endpoint OK")
+		respondObject(w, r, ep)
 		return
 	}
-	fmt.Printf("GET endpoint failed %s/%s %#v\n", vars["account"], vars["endpoint"], err)
+	log.Error(err, "GET endpoint failed")
 	util.RespondNotFound(w, err)
 }
 
 func (g *EPIC) deleteEndpoint(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	err := db.DeleteEndpoint(r.Context(), g.client, vars["account"], vars["endpoint"])
 	if err == nil {
-		fmt.Printf("DELETE endpoint OK %s/%s\n", vars["account"], vars["endpoint"])
+		log.Info("DELETE endpoint OK")
 		util.RespondJSON(w, http.StatusOK, map[string]string{"message": "endpoint deleted"}, util.EmptyHeader)
 		return
 	}
-	fmt.Printf("DELETE endpoint failed %s/%s %#v\n", vars["account"], vars["endpoint"], err)
+	log.Error(err, "DELETE endpoint failed")
 	util.RespondError(w, err)
 }
 
 func (g *EPIC) showGroup(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	group, err := db.ReadGroup(r.Context(), g.client, vars["account"], vars["group"])
 	if err == nil {
-		acctLink, err := g.router.Get("account").URL("account", vars["account"])
+		links, err := routeLinks(r, g.router, map[string]route{
+			"self":           {"group", []string{"account", vars["account"], "group", vars["group"]}},
+			"account":        {"account", []string{"account", vars["account"]}},
+			"create-service": {"group-services", []string{"account", vars["account"], "group", vars["group"]}},
+			"create-proxy":   {"group-proxies", []string{"account", vars["account"], "group", vars["group"]}},
+			"services":       {"group-service-list", []string{"account", vars["account"], "group", vars["group"]}},
+			"proxies":        {"group-proxy-list", []string{"account", vars["account"], "group", vars["group"]}},
+		})
 		if err != nil {
-			fmt.Printf("GET group failed %s/%s: %s\n", vars["account"], vars["group"], err)
+			log.Error(err, "GET group failed")
 			util.RespondError(w, err)
 			return
 		}
-		srvLink, err := g.router.Get("group-services").URL("account", vars["account"], "group", vars["group"])
-		if err != nil {
-			fmt.Printf("GET group failed %s/%s: %s\n", vars["account"], vars["group"], err)
+		group.Links = links
+		log.Info("GET group OK")
+		respondObject(w, r, group)
+		return
+	}
+	log.Error(err, "GET group failed")
+	util.RespondNotFound(w, err)
+}
+
+func (g *EPIC) listGroups(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	// 404 if the account doesn't exist
+	if _, err := db.ReadAccount(r.Context(), g.client, vars["account"]); err != nil {
+		log.Error(err, "GET groups failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	groups, err := db.ListGroups(r.Context(), g.client, vars["account"])
+	if err != nil {
+		log.Error(err, "GET groups failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	list := model.NewGroupList()
+	if list.Links, err = routeLinks(r, g.router, map[string]route{
+		"self":    {"account-groups", []string{"account", vars["account"]}},
+		"account": {"account", []string{"account", vars["account"]}},
+	}); err != nil {
+		log.Error(err, "GET groups failed")
+		util.RespondError(w, err)
+		return
+	}
+	for _, group := range groups {
+		if group.Links, err = routeLinks(r, g.router, map[string]route{
+			"self": {"group", []string{"account", vars["account"], "group", group.Group.Name}},
+		}); err != nil {
+			log.Error(err, "GET groups failed")
 			util.RespondError(w, err)
 			return
 		}
-		proxyLink, err := g.router.Get("group-proxies").URL("account", vars["account"], "group", vars["group"])
-		if err != nil {
-			fmt.Printf("GET group failed %s/%s: %s\n", vars["account"], vars["group"], err)
+		model.Redact(&group)
+		list.Groups = append(list.Groups, group)
+	}
+
+	log.Info("GET groups OK", "count", len(list.Groups))
+	util.RespondJSON(w, http.StatusOK, list, util.EmptyHeader)
+}
+
+func (g *EPIC) listGroupServices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	// 404 if the group doesn't exist
+	if _, err := db.ReadGroup(r.Context(), g.client, vars["account"], vars["group"]); err != nil {
+		log.Error(err, "GET group services failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	services, err := db.ListServices(r.Context(), g.client, vars["account"], client.MatchingLabels{epicv1.OwningLBServiceGroupLabel: vars["group"]})
+	if err != nil {
+		log.Error(err, "GET group services failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	list := model.NewServiceList()
+	if list.Links, err = routeLinks(r, g.router, map[string]route{
+		"self":           {"group-service-list", []string{"account", vars["account"], "group", vars["group"]}},
+		"group":          {"group", []string{"account", vars["account"], "group", vars["group"]}},
+		"create-service": {"group-services", []string{"account", vars["account"], "group", vars["group"]}},
+	}); err != nil {
+		log.Error(err, "GET group services failed")
+		util.RespondError(w, err)
+		return
+	}
+	for _, service := range services {
+		if service.Links, err = routeLinks(r, g.router, map[string]route{
+			"self":  {"service", []string{"account", vars["account"], "service", service.Service.Name}},
+			"group": {"group", []string{"account", vars["account"], "group", vars["group"]}},
+		}); err != nil {
+			log.Error(err, "GET group services failed")
 			util.RespondError(w, err)
 			return
 		}
-		group.Links = model.Links{
-			"self":           r.RequestURI,
-			"account":        acctLink.String(),
-			"create-service": srvLink.String(),
-			"create-proxy":   proxyLink.String(),
-		}
-		util.RespondJSON(w, http.StatusOK, group, util.EmptyHeader)
-		return
+		model.Redact(&service)
+		list.Services = append(list.Services, service)
 	}
-	util.RespondNotFound(w, err)
+
+	log.Info("GET group services OK", "count", len(list.Services))
+	util.RespondJSON(w, http.StatusOK, list, util.EmptyHeader)
 }
 
 func (g *EPIC) showAccount(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	account, err := db.ReadAccount(r.Context(), g.client, vars["account"])
 	if err == nil {
-		routeLink, err := g.router.Get("account-routes").URL("account", vars["account"])
-		if err != nil {
-			fmt.Printf("GET account failed %s: %s\n", vars["account"], err)
-			util.RespondError(w, err)
-			return
-		}
-		sliceLink, err := g.router.Get("account-slices").URL("account", vars["account"])
+		links, err := routeLinks(r, g.router, map[string]route{
+			"self":         {"account", []string{"account", vars["account"]}},
+			"create-route": {"account-routes", []string{"account", vars["account"]}},
+			"create-slice": {"account-slices", []string{"account", vars["account"]}},
+			"summary":      {"account-summary", []string{"account", vars["account"]}},
+			"groups":       {"account-groups", []string{"account", vars["account"]}},
+			"reservations": {"account-reservations", []string{"account", vars["account"]}},
+			"lookup":       {"account-lookup", []string{"account", vars["account"]}},
+		})
 		if err != nil {
-			fmt.Printf("GET account failed %s: %s\n", vars["account"], err)
+			log.Error(err, "GET account failed")
 			util.RespondError(w, err)
 			return
 		}
-		account.Links = model.Links{
-			"self":         r.RequestURI,
-			"create-route": routeLink.String(),
-			"create-slice": sliceLink.String(),
-		}
-		util.RespondJSON(w, http.StatusOK, account, util.EmptyHeader)
+		account.Links = links
+		log.Info("GET account OK")
+		respondObject(w, r, account)
 		return
 	}
+	log.Error(err, "GET account failed")
 	util.RespondNotFound(w, err)
 }
 
 // NewEPIC configures a new EPIC web service instance.
-func NewEPIC(client client.Client, router *mux.Router) *EPIC {
-	return &EPIC{client: client, router: router}
+func NewEPIC(client client.Client, router *mux.Router, recorder record.EventRecorder) *EPIC {
+	return &EPIC{client: client, router: router, recorder: recorder}
 }
 
 // SetupEPICRoutes sets up the provided mux.Router to handle the web
 // service routes.
-func SetupEPICRoutes(router *mux.Router, client client.Client) {
-	epic := NewEPIC(client, router)
+func SetupEPICRoutes(router *mux.Router, client client.Client, recorder record.EventRecorder) {
+	epic := NewEPIC(client, router, recorder)
 	router.HandleFunc("/accounts/{account}/services/{service}/endpoints/{endpoint}", epic.showEndpoint).Methods(http.MethodGet).Name("endpoint")
 	router.HandleFunc("/accounts/{account}/services/{service}/endpoints/{endpoint}", epic.deleteEndpoint).Methods(http.MethodDelete)
-	router.HandleFunc("/accounts/{account}/services/{service}/endpoints", epic.createServiceEndpoint).Methods(http.MethodPost)
+	router.HandleFunc("/accounts/{account}/services/{service}/endpoints", epic.createServiceEndpoint).Methods(http.MethodPost).Name("service-endpoints")
 
 	router.HandleFunc("/accounts/{account}/services/{service}/clusters/{cluster}", epic.showCluster).Methods(http.MethodGet).Name("cluster")
 	router.HandleFunc("/accounts/{account}/services/{service}/clusters/{cluster}", epic.deleteCluster).Methods(http.MethodDelete)
-	router.HandleFunc("/accounts/{account}/services/{service}/clusters", epic.createServiceCluster).Methods(http.MethodPost)
+	router.HandleFunc("/accounts/{account}/services/{service}/clusters", epic.createServiceCluster).Methods(http.MethodPost).Name("service-clusters")
 
 	router.HandleFunc("/accounts/{account}/services/{service}", epic.deleteService).Methods(http.MethodDelete)
 	router.HandleFunc("/accounts/{account}/services/{service}", epic.showService).Methods(http.MethodGet).Name("service")
 
 	router.HandleFunc("/accounts/{account}/groups/{group}/services", epic.createService).Methods(http.MethodPost).Name("group-services")
+	router.HandleFunc("/accounts/{account}/groups/{group}/services", epic.listGroupServices).Methods(http.MethodGet).Name("group-service-list")
 	router.HandleFunc("/accounts/{account}/groups/{group}", epic.showGroup).Methods(http.MethodGet).Name("group")
+	router.HandleFunc("/accounts/{account}/groups", epic.listGroups).Methods(http.MethodGet).Name("account-groups")
 
+	router.HandleFunc("/accounts/{account}/summary", epic.showSummary).Methods(http.MethodGet).Name("account-summary")
 	router.HandleFunc("/accounts/{account}", epic.showAccount).Methods(http.MethodGet).Name("account")
 }
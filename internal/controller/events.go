@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// Event reasons. These show up in the "Reason" column of "kubectl
+// describe".
+const (
+	reasonCreated  = "Created"
+	reasonUpdated  = "Updated"
+	reasonDeleted  = "Deleted"
+	reasonConflict = "Conflict"
+)
+
+// recordEvent emits a Kubernetes Event on obj. The caller's identity
+// is appended to the message so operators can see who asked the web
+// service to make the change. "kubectl describe" finds events by
+// their object's UID, so obj should have been read from (or written
+// to) the cluster. If obj is nil then no event is emitted.
+func recordEvent(recorder record.EventRecorder, r *http.Request, obj runtime.Object, eventType string, reason string, messageFmt string, args ...interface{}) {
+	if recorder == nil || obj == nil {
+		return
+	}
+	recorder.Eventf(obj, eventType, reason, "%s by %s via web service", fmt.Sprintf(messageFmt, args...), util.CallerIdentity(r))
+}
+
+// recordNormal emits a Normal event on obj and on the Account that
+// owns it, so "kubectl describe account" shows a timeline of the
+// changes that were made in the account.
+func recordNormal(recorder record.EventRecorder, cl client.Reader, r *http.Request, accountName string, obj runtime.Object, reason string, kind string, name string) {
+	recordEvent(recorder, r, obj, corev1.EventTypeNormal, reason, "%s %s %s", kind, name, pastTense(reason))
+	recordEvent(recorder, r, accountRef(r.Context(), cl, accountName), corev1.EventTypeNormal, reason, "%s %s %s", kind, name, pastTense(reason))
+}
+
+// recordConflict emits a Warning event saying that a duplicate create
+// was rejected. obj is the request's object, which doesn't have a
+// UID, so the event goes on the existing object with obj's name.
+func recordConflict(recorder record.EventRecorder, cl client.Reader, r *http.Request, obj client.Object, kind string, name string) {
+	if recorder == nil {
+		return
+	}
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := cl.Get(r.Context(), client.ObjectKeyFromObject(obj), existing); err != nil {
+		log.FromContext(r.Context()).Info("not recording conflict event", "error", err.Error())
+		return
+	}
+	recordEvent(recorder, r, existing, corev1.EventTypeWarning, reasonConflict, "duplicate create of %s %s rejected", kind, name)
+}
+
+// accountRef reads the Account named accountName so it can be the
+// target of an Event. It returns nil if the Account can't be read.
+func accountRef(ctx context.Context, cl client.Reader, accountName string) runtime.Object {
+	account := &epicv1.Account{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: accountName}, account); err != nil {
+		log.FromContext(ctx).Info("not recording account event", "account", accountName, "error", err.Error())
+		return nil
+	}
+	return account
+}
+
+func pastTense(reason string) string {
+	switch reason {
+	case reasonCreated:
+		return "created"
+	case reasonUpdated:
+		return "updated"
+	case reasonDeleted:
+		return "deleted"
+	}
+	return reason
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/pool"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+const (
+	// detailPoolExhausted is the detail code of responses to requests
+	// that failed because a service prefix has no free addresses.
+	detailPoolExhausted = "pool-exhausted"
+)
+
+var (
+	// The allocator runs in EPIC's admission webhook. The API server
+	// wraps every webhook rejection in a status whose message starts
+	// like this, whatever the webhook said; see ToStatusErr in
+	// k8s.io/apiserver/pkg/admission/plugin/webhook/errors/statuserror.go.
+	webhookDenied = regexp.MustCompile(`^admission webhook ".*" denied the request`)
+)
+
+// admissionDenied indicates whether err is an admission webhook
+// rejecting a request, as opposed to the API server or the network
+// failing.
+func admissionDenied(err error) bool {
+	status, ok := err.(errors.APIStatus)
+	if !ok {
+		return false
+	}
+	return webhookDenied.MatchString(status.Status().Message)
+}
+
+// respondIfExhausted checks whether a create failed because the
+// group's service prefix ran out of addresses. If so it sends a 507
+// "insufficient storage" response and returns true, otherwise it does
+// nothing and returns false.
+//
+// If we let the allocator pick the address (i.e., address is "") and
+// a webhook denied the create then we count the pool's free
+// addresses. The denial could have come from any webhook, for any
+// reason, so we only blame the pool if it has none; the client
+// shouldn't retry until someone frees an address or grows the pool.
+// Every other denial is left to the caller.
+func respondIfExhausted(ctx context.Context, w http.ResponseWriter, cl client.Client, prefixName string, address string, createErr error) bool {
+	if address != "" || !admissionDenied(createErr) {
+		return false
+	}
+
+	free, err := prefixFree(ctx, cl, prefixName)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "checking pool usage failed", "prefix", prefixName)
+		return false
+	}
+	if free != 0 {
+		return false
+	}
+
+	util.RespondDetail(w, http.StatusInsufficientStorage, detailPoolExhausted,
+		fmt.Sprintf("service prefix %s has no free addresses: %s", prefixName, createErr), util.EmptyHeader)
+	return true
+}
+
+// prefixFree counts the free addresses in a service prefix's pool. If
+// there are more than fit in an int64 it returns the largest int64.
+func prefixFree(ctx context.Context, cl client.Client, prefixName string) (int64, error) {
+	prefix, err := db.ReadPrefix(ctx, cl, prefixName)
+	if err != nil {
+		return 0, err
+	}
+	addressPool, err := pool.Parse(prefix.Spec.Pool)
+	if err != nil {
+		return 0, err
+	}
+	usage, err := db.PrefixUsage(ctx, cl, prefix, addressPool)
+	if err != nil {
+		return 0, err
+	}
+	if !usage.Free.IsInt64() {
+		return 1<<63 - 1, nil
+	}
+	return usage.Free.Int64(), nil
+}
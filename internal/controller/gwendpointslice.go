@@ -2,14 +2,15 @@ package controller
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"regexp"
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
 	"github.com/gorilla/mux"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"acnodal.io/epic/web-service/internal/db"
 	"acnodal.io/epic/web-service/internal/model"
@@ -23,8 +24,9 @@ var (
 // SliceController implements the server side of the GWEndpointSlice web service
 // protocol.
 type SliceController struct {
-	client client.Client
-	router *mux.Router
+	client   client.Client
+	router   *mux.Router
+	recorder record.EventRecorder
 }
 
 func (g *SliceController) create(w http.ResponseWriter, r *http.Request) {
@@ -33,10 +35,11 @@ func (g *SliceController) create(w http.ResponseWriter, r *http.Request) {
 		body model.Slice
 	)
 	urlParams := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
-		fmt.Printf("POST endpointSlice failed %s\n", err)
+		log.Error(err, "POST endpointSlice failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -52,10 +55,11 @@ func (g *SliceController) create(w http.ResponseWriter, r *http.Request) {
 	// UID since that's unique.
 	body.Slice.Namespace = epicv1.AccountNamespace(urlParams["account"])
 	body.Slice.Name = body.Slice.Spec.ClientRef.UID
+	log = log.WithValues("slice", body.Slice.Name)
 
-	selfURL, err := g.router.Get("slice").URL("account", urlParams["account"], "slice", body.Slice.Name)
+	selfURL, err := util.RouteURL(r, g.router, "slice", "account", urlParams["account"], "slice", body.Slice.Name)
 	if err != nil {
-		fmt.Printf("POST endpointSlice failed %s/%s: %s\n", urlParams["account"], body.Slice.Name, err)
+		log.Error(err, "POST endpointSlice failed")
 		util.RespondError(w, err)
 		return
 	}
@@ -65,62 +69,96 @@ func (g *SliceController) create(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		matches := duplicateEndpointSlice.FindStringSubmatch(err.Error())
 		if len(matches) > 0 {
-			fmt.Printf("POST endpointSlice 409/duplicate %s/%s\n", urlParams["account"], body.Slice.Name)
+			log.Info("POST endpointSlice 409/duplicate")
+			recordConflict(g.recorder, g.client, r, &body.Slice, "GWEndpointSlice", body.Slice.Name)
 
 			// We already had that endpointSlice, but we can return what we hope the
 			// client needs to set up the tunnels on its end
 			util.RespondConflict(
 				w,
-				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL.String()}},
-				map[string]string{"Location": selfURL.String()},
+				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL}},
+				map[string]string{"Location": selfURL},
 			)
 			return
 		}
 
 		// Something else went wrong
-		fmt.Printf("POST endpointSlice failed %#v\n", err)
+		log.Error(err, "POST endpointSlice failed")
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("POST endpointSlice OK %v %#v\n", urlParams["account"], body.Slice.Spec)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	log.Info("POST endpointSlice OK", "spec", body.Slice.Spec)
+	recordNormal(g.recorder, g.client, r, urlParams["account"], &body.Slice, reasonCreated, "GWEndpointSlice", body.Slice.Name)
+	http.Redirect(w, r, selfURL, http.StatusFound)
 }
 
 func (g *SliceController) show(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	endpointSlice, err := db.ReadSlice(r.Context(), g.client, vars["account"], vars["slice"])
 	if err == nil {
-		endpointSlice.Slice.ObjectMeta = metav1.ObjectMeta{}
+		selfURL, err := util.RouteURL(r, g.router, "slice", "account", vars["account"], "slice", vars["slice"])
+		if err != nil {
+			log.Error(err, "GET endpointSlice failed")
+			util.RespondError(w, err)
+			return
+		}
 		endpointSlice.Links = model.Links{
-			"self": fmt.Sprintf("%s", r.RequestURI),
+			"self": selfURL,
+		}
+		embeds, err := embedParam(r, embedRoutes)
+		if err != nil {
+			log.Error(err, "GET endpointSlice failed")
+			util.RespondBad(w, err)
+			return
+		}
+		if len(embeds) > 0 {
+			if endpointSlice.Embedded, err = newEmbedder(g.client, g.router, r).forSlice(endpointSlice, embeds); err != nil {
+				log.Error(err, "GET endpointSlice failed")
+				util.RespondError(w, err)
+				return
+			}
 		}
-		fmt.Printf("GET endpointSlice OK %s/%s\n", vars["account"], vars["slice"])
-		util.RespondJSON(w, http.StatusOK, endpointSlice, util.EmptyHeader)
+		log.Info("GET endpointSlice OK")
+		respondObject(w, r, endpointSlice)
 		return
 	}
-	fmt.Printf("GET endpointSlice failed %s/%s %#v\n", vars["account"], vars["slice"], err)
+	log.Error(err, "GET endpointSlice failed")
 	util.RespondNotFound(w, err)
 }
 
 func (g *SliceController) del(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	// Read the slice first so the event can refer to it
+	slice, err := db.ReadSlice(r.Context(), g.client, vars["account"], vars["slice"])
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "DELETE endpointSlice failed")
+		util.RespondError(w, err)
+		return
+	}
+	found := err == nil
 
 	// Delete the CR
 	if err := db.DeleteSlice(r.Context(), g.client, vars["account"], vars["slice"]); err != nil {
 		matches := multiClusterLB.FindStringSubmatch(err.Error())
 		if len(matches) > 0 {
-			fmt.Printf("service %s has clusters: %s\n", vars["slice"], err)
+			log.Info("endpointSlice has clusters", "error", err.Error())
 			util.RespondConflict(w, map[string]interface{}{"message": err.Error()}, util.EmptyHeader)
 			return
 		}
 
-		fmt.Printf("DELETE endpointSlice failed %s/%s %#v\n", vars["account"], vars["slice"], err)
+		log.Error(err, "DELETE endpointSlice failed")
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("DELETE endpointSlice OK %s/%s\n", vars["account"], vars["slice"])
+	log.Info("DELETE endpointSlice OK")
+	if found {
+		recordNormal(g.recorder, g.client, r, vars["account"], &slice.Slice, reasonDeleted, "GWEndpointSlice", vars["slice"])
+	}
 	util.RespondJSON(w, http.StatusOK, map[string]string{"message": "delete successful"}, map[string]string{})
 	return
 }
@@ -133,11 +171,12 @@ func (g *SliceController) put(w http.ResponseWriter, r *http.Request) {
 		body model.Slice
 	)
 	urlParams := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	// See if the slice exists, return 404 if not
-	_, err = db.ReadSlice(r.Context(), g.client, urlParams["account"], urlParams["slice"])
+	existing, err := db.ReadSlice(r.Context(), g.client, urlParams["account"], urlParams["slice"])
 	if err != nil {
-		fmt.Printf("PUT endpointSlice failed %s/%s %#v\n", urlParams["account"], urlParams["slice"], err)
+		log.Error(err, "PUT endpointSlice failed")
 		util.RespondNotFound(w, err)
 		return
 	}
@@ -145,7 +184,7 @@ func (g *SliceController) put(w http.ResponseWriter, r *http.Request) {
 	// Decode the request body.
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
-		fmt.Printf("PUT endpointSlice failed %s/%s %s\n", urlParams["account"], urlParams["slice"], err)
+		log.Error(err, "PUT endpointSlice failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -153,27 +192,28 @@ func (g *SliceController) put(w http.ResponseWriter, r *http.Request) {
 	// Update the slice.
 	err = db.UpdateSlice(r.Context(), g.client, urlParams["account"], urlParams["slice"], &body.Slice)
 	if err != nil {
-		fmt.Printf("PUT endpointSlice failed %s\n", err)
+		log.Error(err, "PUT endpointSlice failed")
 		util.RespondError(w, err)
 		return
 	}
 
 	// Redirect back to this slice's GET endpoint.
-	selfURL, err := g.router.Get("slice").URL("account", urlParams["account"], "slice", urlParams["slice"])
+	selfURL, err := util.RouteURL(r, g.router, "slice", "account", urlParams["account"], "slice", urlParams["slice"])
 	if err != nil {
-		fmt.Printf("PUT endpointSlice failed %s/%s: %s\n", urlParams["account"], urlParams["slice"], err)
+		log.Error(err, "PUT endpointSlice failed")
 		util.RespondError(w, err)
 		return
 	}
-	fmt.Printf("PUT endpointSlice OK %v %#v\n", urlParams["account"], body.Slice.Spec)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	log.Info("PUT endpointSlice OK", "spec", body.Slice.Spec)
+	recordNormal(g.recorder, g.client, r, urlParams["account"], &existing.Slice, reasonUpdated, "GWEndpointSlice", urlParams["slice"])
+	http.Redirect(w, r, selfURL, http.StatusFound)
 	return
 }
 
 // SetupSliceRoutes sets up the provided mux.Router to handle the web
 // service routes.
-func SetupSliceRoutes(router *mux.Router, client client.Client) {
-	sliceCtrl := &SliceController{client: client, router: router}
+func SetupSliceRoutes(router *mux.Router, client client.Client, recorder record.EventRecorder) {
+	sliceCtrl := &SliceController{client: client, router: router, recorder: recorder}
 	router.HandleFunc("/accounts/{account}/slices/{slice}", sliceCtrl.del).Methods(http.MethodDelete)
 	router.HandleFunc("/accounts/{account}/slices/{slice}", sliceCtrl.show).Methods(http.MethodGet).Name("slice")
 	router.HandleFunc("/accounts/{account}/slices/{slice}", sliceCtrl.put).Methods(http.MethodPut)
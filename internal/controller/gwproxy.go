@@ -2,14 +2,15 @@ package controller
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"regexp"
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
 	"github.com/gorilla/mux"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"acnodal.io/epic/web-service/internal/db"
 	"acnodal.io/epic/web-service/internal/model"
@@ -23,14 +24,16 @@ var (
 // GWProxy implements the server side of the GWProxy web service
 // protocol.
 type GWProxy struct {
-	client client.Client
-	router *mux.Router
+	client   client.Client
+	router   *mux.Router
+	recorder record.EventRecorder
 }
 
 // ProxyCreateRequest contains the data from a web service request to
 // create a GWProxy.
 type ProxyCreateRequest struct {
 	Proxy epicv1.GWProxy
+	AddressRequest
 }
 
 // createProxy handles PureLB proxy announcements. They're sent from
@@ -42,10 +45,11 @@ func (g *GWProxy) create(w http.ResponseWriter, r *http.Request) {
 		body ProxyCreateRequest
 	)
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
-		fmt.Printf("POST proxy failed %#v\n", err)
+		log.Error(err, "POST proxy failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -54,7 +58,7 @@ func (g *GWProxy) create(w http.ResponseWriter, r *http.Request) {
 	// which we'll allocate the address
 	group, err := db.ReadGroup(r.Context(), g.client, vars["account"], vars["group"])
 	if err != nil {
-		fmt.Printf("POST proxy failed %#v\n", err)
+		log.Error(err, "POST proxy failed")
 		util.RespondNotFound(w, err)
 		return
 	}
@@ -73,91 +77,227 @@ func (g *GWProxy) create(w http.ResponseWriter, r *http.Request) {
 	body.Proxy.Namespace = group.Group.Namespace
 	body.Proxy.Name = body.Proxy.Spec.ClientRef.UID
 	body.Proxy.Spec.DisplayName = body.Proxy.Spec.ClientRef.Name
+	log = log.WithValues("proxy", body.Proxy.Name)
 
-	selfURL, err := g.router.Get("proxy").URL("account", vars["account"], "proxy", body.Proxy.Name)
+	selfURL, err := util.RouteURL(r, g.router, "proxy", "account", vars["account"], "proxy", body.Proxy.Name)
 	if err != nil {
-		fmt.Printf("POST proxy failed %s/%s/%s: %s\n", vars["account"], vars["group"], body.Proxy.Name, err)
+		log.Error(err, "POST proxy failed")
 		util.RespondError(w, err)
 		return
 	}
 
+	// If the client asked for an address, or has a reservation, then
+	// check that it can have it
+	claim := addressClaim{account: vars["account"], kind: "GWProxy", name: body.Proxy.Name, reservation: db.ProxyReservationName(body.Proxy.Spec.ClientRef)}
+	address, reservation, ok := requestAddress(r.Context(), w, g.client, body.Proxy.Labels[epicv1.OwningServicePrefixLabel], body.AddressRequest, claim)
+	if !ok {
+		log.Info("POST proxy failed, address unavailable", "address", body.Address, "hint", body.AddressHint, "reservation", body.Reservation)
+		return
+	}
+	if address != "" {
+		body.Proxy.Spec.PublicAddress = address
+	}
+
 	// Create the resource
 	err = g.client.Create(r.Context(), &body.Proxy)
 	if err != nil {
 		matches := duplicateProxy.FindStringSubmatch(err.Error())
 		if len(matches) > 0 {
-			fmt.Printf("POST proxy 409/duplicate %s/%s\n", vars["account"], body.Proxy.Name)
+			log.Info("POST proxy 409/duplicate")
+			recordConflict(g.recorder, g.client, r, &body.Proxy, "GWProxy", body.Proxy.Name)
 
 			// We already had that proxy, but we can return what we hope the
 			// client needs to set up the tunnels on its end
 			util.RespondConflict(
 				w,
-				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL.String()}},
-				map[string]string{"Location": selfURL.String()},
+				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL}},
+				map[string]string{"Location": selfURL},
 			)
 			return
 		}
 
+		// The address that the client asked for might have been taken
+		if respondIfAddressTaken(r.Context(), w, g.client, body.Proxy.Labels[epicv1.OwningServicePrefixLabel], address, reservation, claim, err) {
+			log.Info("POST proxy 409/address in use", "address", address)
+			return
+		}
+
+		// The allocator might have run out of addresses
+		if respondIfExhausted(r.Context(), w, g.client, body.Proxy.Labels[epicv1.OwningServicePrefixLabel], address, err) {
+			log.Error(err, "POST proxy failed, pool exhausted")
+			return
+		}
+
 		// Something else went wrong
-		fmt.Printf("POST proxy failed %#v\n", err)
+		log.Error(err, "POST proxy failed")
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("POST proxy OK %v %#v\n", vars["account"], body.Proxy.Spec)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	// The new proxy has the reserved address so the reservation isn't
+	// needed
+	if reservation != "" {
+		releaseClaimed(r.Context(), g.client, vars["account"], reservation)
+	}
+
+	log.Info("POST proxy OK", "spec", body.Proxy.Spec)
+	recordNormal(g.recorder, g.client, r, vars["account"], &body.Proxy, reasonCreated, "GWProxy", body.Proxy.Name)
+	http.Redirect(w, r, selfURL, http.StatusFound)
 }
 
 func (g *GWProxy) get(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	proxy, err := db.ReadProxy(r.Context(), g.client, vars["account"], vars["proxy"])
 	if err == nil {
-		groupLink, err := g.router.Get("group").URL("account", vars["account"], "group", proxy.Proxy.Labels[epicv1.OwningLBServiceGroupLabel])
+		links, err := routeLinks(r, g.router, map[string]route{
+			"self":  {"proxy", []string{"account", vars["account"], "proxy", vars["proxy"]}},
+			"group": {"group", []string{"account", vars["account"], "group", proxy.Proxy.Labels[epicv1.OwningLBServiceGroupLabel]}},
+		})
 		if err != nil {
-			fmt.Printf("GET proxy failed %s/%s: %s\n", vars["account"], vars["group"], err)
+			log.Error(err, "GET proxy failed")
 			util.RespondError(w, err)
 			return
 		}
-		proxy.Links = model.Links{
-			"self":  fmt.Sprintf("%s", r.RequestURI),
-			"group": groupLink.String(),
+		proxy.Links = links
+		embeds, err := embedParam(r, embedGroup, embedRoutes, embedSlices)
+		if err != nil {
+			log.Error(err, "GET proxy failed")
+			util.RespondBad(w, err)
+			return
+		}
+		if len(embeds) > 0 {
+			if proxy.Embedded, err = newEmbedder(g.client, g.router, r).forProxy(proxy, embeds); err != nil {
+				log.Error(err, "GET proxy failed")
+				util.RespondError(w, err)
+				return
+			}
 		}
-		proxy.Proxy.ObjectMeta = metav1.ObjectMeta{}
-		fmt.Printf("GET proxy OK %s/%s\n", vars["account"], vars["proxy"])
-		util.RespondJSON(w, http.StatusOK, proxy, util.EmptyHeader)
+		log.Info("GET proxy OK")
+		respondObject(w, r, proxy)
 		return
 	}
-	fmt.Printf("GET proxy failed %s/%s %#v\n", vars["account"], vars["proxy"], err)
+	log.Error(err, "GET proxy failed")
 	util.RespondNotFound(w, err)
 }
 
+func (g *GWProxy) list(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	// 404 if the group doesn't exist
+	if _, err := db.ReadGroup(r.Context(), g.client, vars["account"], vars["group"]); err != nil {
+		log.Error(err, "GET group proxies failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	proxies, err := db.ListProxies(r.Context(), g.client, vars["account"], client.MatchingLabels{epicv1.OwningLBServiceGroupLabel: vars["group"]})
+	if err != nil {
+		log.Error(err, "GET group proxies failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	list := model.NewProxyList()
+	if list.Links, err = routeLinks(r, g.router, map[string]route{
+		"self":         {"group-proxy-list", []string{"account", vars["account"], "group", vars["group"]}},
+		"group":        {"group", []string{"account", vars["account"], "group", vars["group"]}},
+		"create-proxy": {"group-proxies", []string{"account", vars["account"], "group", vars["group"]}},
+	}); err != nil {
+		log.Error(err, "GET group proxies failed")
+		util.RespondError(w, err)
+		return
+	}
+	for _, proxy := range proxies {
+		if proxy.Links, err = routeLinks(r, g.router, map[string]route{
+			"self":  {"proxy", []string{"account", vars["account"], "proxy", proxy.Proxy.Name}},
+			"group": {"group", []string{"account", vars["account"], "group", vars["group"]}},
+		}); err != nil {
+			log.Error(err, "GET group proxies failed")
+			util.RespondError(w, err)
+			return
+		}
+		model.Redact(&proxy)
+		list.Proxies = append(list.Proxies, proxy)
+	}
+
+	log.Info("GET group proxies OK", "count", len(list.Proxies))
+	util.RespondJSON(w, http.StatusOK, list, util.EmptyHeader)
+}
+
 func (g *GWProxy) del(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	hold, err := parseHold(r)
+	if err != nil {
+		log.Error(err, "DELETE proxy failed")
+		util.RespondBad(w, err)
+		return
+	}
+
+	// Read the proxy first so we know which address to hold
+	proxy, err := db.ReadProxy(r.Context(), g.client, vars["account"], vars["proxy"])
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "DELETE proxy failed")
+		util.RespondError(w, err)
+		return
+	}
+	found := err == nil
+
+	// Hold the address before the delete so a create can't take it
+	// in between
+	var reservation model.Reservation
+	held := false
+	if found && hold.hold {
+		reservation = proxyReservation(&proxy.Proxy, hold.name)
+		if held, err = holdAddress(r.Context(), g.client, vars["account"], reservation); err != nil {
+			log.Error(err, "DELETE proxy failed")
+			util.RespondError(w, err)
+			return
+		}
+	}
 
 	// Delete the CR
 	if err := db.DeleteProxy(r.Context(), g.client, vars["account"], vars["proxy"]); err != nil {
+		if held {
+			releaseClaimed(r.Context(), g.client, vars["account"], reservation.Name)
+		}
+
 		matches := multiClusterLB.FindStringSubmatch(err.Error())
 		if len(matches) > 0 {
-			fmt.Printf("service %s has clusters: %s\n", vars["proxy"], err)
+			log.Info("proxy has clusters", "error", err.Error())
 			util.RespondConflict(w, map[string]interface{}{"message": err.Error()}, util.EmptyHeader)
 			return
 		}
 
-		fmt.Printf("DELETE proxy failed %s/%s %#v\n", vars["account"], vars["proxy"], err)
+		log.Error(err, "DELETE proxy failed")
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("DELETE proxy OK %s/%s\n", vars["account"], vars["proxy"])
-	util.RespondJSON(w, http.StatusOK, map[string]string{"message": "delete successful"}, map[string]string{})
+	response := map[string]string{"message": "delete successful"}
+	if held {
+		if link := reservationLink(r, g.router, vars["account"], reservation.Name); link != "" {
+			response["reservation"] = link
+		}
+	}
+
+	log.Info("DELETE proxy OK")
+	if found {
+		recordNormal(g.recorder, g.client, r, vars["account"], &proxy.Proxy, reasonDeleted, "GWProxy", vars["proxy"])
+	}
+	util.RespondJSON(w, http.StatusOK, response, map[string]string{})
 	return
 }
 
 // SetupGWProxyRoutes sets up the provided mux.Router to handle the web
 // service routes.
-func SetupGWProxyRoutes(router *mux.Router, client client.Client) {
-	proxyCon := &GWProxy{client: client, router: router}
+func SetupGWProxyRoutes(router *mux.Router, client client.Client, recorder record.EventRecorder) {
+	proxyCon := &GWProxy{client: client, router: router, recorder: recorder}
 	router.HandleFunc("/accounts/{account}/proxies/{proxy}", proxyCon.del).Methods(http.MethodDelete)
 	router.HandleFunc("/accounts/{account}/proxies/{proxy}", proxyCon.get).Methods(http.MethodGet).Name("proxy")
 	router.HandleFunc("/accounts/{account}/groups/{group}/proxies", proxyCon.create).Methods(http.MethodPost).Name("group-proxies")
+	router.HandleFunc("/accounts/{account}/groups/{group}/proxies", proxyCon.list).Methods(http.MethodGet).Name("group-proxy-list")
 }
@@ -2,14 +2,15 @@ package controller
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"regexp"
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
 	"github.com/gorilla/mux"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"acnodal.io/epic/web-service/internal/db"
 	"acnodal.io/epic/web-service/internal/model"
@@ -23,8 +24,9 @@ var (
 // GWRoute implements the server side of the GWRoute web service
 // protocol.
 type GWRoute struct {
-	client client.Client
-	router *mux.Router
+	client   client.Client
+	router   *mux.Router
+	recorder record.EventRecorder
 }
 
 // RouteCreateRequest contains the data from a web service request to
@@ -35,6 +37,7 @@ type RouteCreateRequest struct {
 
 func (g *GWRoute) create(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	var (
 		body RouteCreateRequest
 		err  error
@@ -42,6 +45,7 @@ func (g *GWRoute) create(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Error(err, "POST route failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -57,10 +61,11 @@ func (g *GWRoute) create(w http.ResponseWriter, r *http.Request) {
 	// since that's unique.
 	body.Route.Namespace = epicv1.AccountNamespace(vars["account"])
 	body.Route.Name = body.Route.Spec.ClientRef.UID
+	log = log.WithValues("route", body.Route.Name)
 
-	selfURL, err := g.router.Get("route").URL("account", vars["account"], "route", body.Route.Name)
+	selfURL, err := util.RouteURL(r, g.router, "route", "account", vars["account"], "route", body.Route.Name)
 	if err != nil {
-		fmt.Printf("POST route failed %s/%s/%s: %s\n", vars["account"], vars["service"], body.Route.Name, err)
+		log.Error(err, "POST route failed")
 		util.RespondError(w, err)
 		return
 	}
@@ -69,56 +74,91 @@ func (g *GWRoute) create(w http.ResponseWriter, r *http.Request) {
 	if err := g.client.Create(r.Context(), &body.Route); err != nil {
 		matches := duplicateRoute.FindStringSubmatch(err.Error())
 		if len(matches) > 0 {
-			fmt.Printf("POST route 409/duplicate %s/%s\n", vars["account"], body.Route.Name)
+			log.Info("POST route 409/duplicate")
+			recordConflict(g.recorder, g.client, r, &body.Route, "GWRoute", body.Route.Name)
 
 			// We already had that route, but we can return what we hope the
 			// client needs.
 			util.RespondConflict(
 				w,
-				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL.String()}},
-				map[string]string{"Location": selfURL.String()},
+				map[string]interface{}{"message": err.Error(), "link": model.Links{"self": selfURL}},
+				map[string]string{"Location": selfURL},
 			)
 			return
 		}
 
 		// Something else went wrong
-		fmt.Printf("POST route failed %#v %#v\n", body, err)
+		log.Error(err, "POST route failed", "spec", body.Route.Spec)
 		util.RespondError(w, err)
 		return
 	}
 
-	fmt.Printf("POST route OK %#v\n", body.Route.Spec)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	log.Info("POST route OK", "spec", body.Route.Spec)
+	recordNormal(g.recorder, g.client, r, vars["account"], &body.Route, reasonCreated, "GWRoute", body.Route.Name)
+	http.Redirect(w, r, selfURL, http.StatusFound)
 	return
 }
 
 func (g *GWRoute) show(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
 	route, err := db.ReadRoute(r.Context(), g.client, vars["account"], vars["route"])
 	if err == nil {
+		selfURL, err := util.RouteURL(r, g.router, "route", "account", vars["account"], "route", vars["route"])
+		if err != nil {
+			log.Error(err, "GET route failed")
+			util.RespondError(w, err)
+			return
+		}
 		route.Links = model.Links{
-			"self": fmt.Sprintf("%s", r.RequestURI),
+			"self": selfURL,
 		}
-		route.Route.ObjectMeta = metav1.ObjectMeta{}
 
-		fmt.Printf("GET route OK %s/%s\n", vars["account"], vars["route"])
-		util.RespondJSON(w, http.StatusOK, route, util.EmptyHeader)
+		embeds, err := embedParam(r, embedProxies, embedSlices)
+		if err != nil {
+			log.Error(err, "GET route failed")
+			util.RespondBad(w, err)
+			return
+		}
+		if len(embeds) > 0 {
+			if route.Embedded, err = newEmbedder(g.client, g.router, r).forRoute(route, embeds); err != nil {
+				log.Error(err, "GET route failed")
+				util.RespondError(w, err)
+				return
+			}
+		}
+		log.Info("GET route OK")
+		respondObject(w, r, route)
 		return
 	}
-	fmt.Printf("GET route failed %s/%s %#v\n", vars["account"], vars["route"], err)
+	log.Error(err, "GET route failed")
 	util.RespondNotFound(w, err)
 }
 
 func (g *GWRoute) del(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	err := db.DeleteRoute(r.Context(), g.client, vars["account"], vars["route"])
-	if err == nil {
-		fmt.Printf("DELETE route OK %s/%s\n", vars["account"], vars["route"])
-		util.RespondJSON(w, http.StatusOK, map[string]string{"message": "route deleted"}, util.EmptyHeader)
+	log := log.FromContext(r.Context())
+
+	// Read the route first so the event can refer to it
+	route, err := db.ReadRoute(r.Context(), g.client, vars["account"], vars["route"])
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "DELETE route failed")
+		util.RespondError(w, err)
 		return
 	}
-	fmt.Printf("DELETE route failed %s/%s %#v\n", vars["account"], vars["route"], err)
-	util.RespondError(w, err)
+	found := err == nil
+
+	if err := db.DeleteRoute(r.Context(), g.client, vars["account"], vars["route"]); err != nil {
+		log.Error(err, "DELETE route failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	log.Info("DELETE route OK")
+	if found {
+		recordNormal(g.recorder, g.client, r, vars["account"], &route.Route, reasonDeleted, "GWRoute", vars["route"])
+	}
+	util.RespondJSON(w, http.StatusOK, map[string]string{"message": "route deleted"}, util.EmptyHeader)
 }
 
 // put implements the HTTP PUT method, which updates an existing
@@ -129,11 +169,12 @@ func (g *GWRoute) put(w http.ResponseWriter, r *http.Request) {
 		body model.Route
 	)
 	urlParams := mux.Vars(r)
+	log := log.FromContext(r.Context())
 
 	// See if the route exists, return 404 if not
-	_, err = db.ReadRoute(r.Context(), g.client, urlParams["account"], urlParams["route"])
+	existing, err := db.ReadRoute(r.Context(), g.client, urlParams["account"], urlParams["route"])
 	if err != nil {
-		fmt.Printf("PUT route failed %s/%s %#v\n", urlParams["account"], urlParams["route"], err)
+		log.Error(err, "PUT route failed")
 		util.RespondNotFound(w, err)
 		return
 	}
@@ -141,7 +182,7 @@ func (g *GWRoute) put(w http.ResponseWriter, r *http.Request) {
 	// Decode the request body.
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
-		fmt.Printf("PUT route failed %s/%s %s\n", urlParams["account"], urlParams["route"], err)
+		log.Error(err, "PUT route failed")
 		util.RespondBad(w, err)
 		return
 	}
@@ -155,27 +196,28 @@ func (g *GWRoute) put(w http.ResponseWriter, r *http.Request) {
 	// Update the route.
 	err = db.UpdateRoute(r.Context(), g.client, urlParams["account"], urlParams["route"], &body.Route)
 	if err != nil {
-		fmt.Printf("PUT route failed %s\n", err)
+		log.Error(err, "PUT route failed")
 		util.RespondError(w, err)
 		return
 	}
 
 	// Redirect back to this route's GET endpoint.
-	selfURL, err := g.router.Get("route").URL("account", urlParams["account"], "route", urlParams["route"])
+	selfURL, err := util.RouteURL(r, g.router, "route", "account", urlParams["account"], "route", urlParams["route"])
 	if err != nil {
-		fmt.Printf("PUT route failed %s/%s: %s\n", urlParams["account"], urlParams["route"], err)
+		log.Error(err, "PUT route failed")
 		util.RespondError(w, err)
 		return
 	}
-	fmt.Printf("PUT route OK %v %#v\n", urlParams["account"], body.Route.Spec)
-	http.Redirect(w, r, selfURL.String(), http.StatusFound)
+	log.Info("PUT route OK", "spec", body.Route.Spec)
+	recordNormal(g.recorder, g.client, r, urlParams["account"], &existing.Route, reasonUpdated, "GWRoute", urlParams["route"])
+	http.Redirect(w, r, selfURL, http.StatusFound)
 	return
 }
 
 // SetupEPICRoutes sets up the provided mux.Router to handle the web
 // service routes.
-func SetupGWRouteRoutes(router *mux.Router, client client.Client) {
-	routeCon := &GWRoute{client: client, router: router}
+func SetupGWRouteRoutes(router *mux.Router, client client.Client, recorder record.EventRecorder) {
+	routeCon := &GWRoute{client: client, router: router, recorder: recorder}
 	router.HandleFunc("/accounts/{account}/routes/{route}", routeCon.show).Methods(http.MethodGet).Name("route")
 	router.HandleFunc("/accounts/{account}/routes/{route}", routeCon.del).Methods(http.MethodDelete)
 	router.HandleFunc("/accounts/{account}/routes/{route}", routeCon.put).Methods(http.MethodPut)
@@ -5,15 +5,55 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"acnodal.io/epic/web-service/internal/health"
 	"acnodal.io/epic/web-service/internal/util"
 )
 
+// checkResult is the result of one health check on the wire.
+type checkResult struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	util.RespondJSON(w, http.StatusOK, map[string]string{"healthy": "yes"}, util.EmptyHeader)
 }
 
+// checkHandler returns a handler that runs checks and responds with
+// 200 if they all pass or 503 if any of them fail. If the request
+// has a "verbose" query parameter then the response includes each
+// check's result.
+func checkHandler(checks health.Checks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, verbose := r.URL.Query()["verbose"]
+
+		status := http.StatusOK
+		results := []checkResult{}
+		for _, check := range checks {
+			result := checkResult{Name: check.Name, OK: true}
+			if err := check.Check(r); err != nil {
+				result.OK = false
+				result.Error = err.Error()
+				status = http.StatusServiceUnavailable
+			}
+			results = append(results, result)
+		}
+
+		body := map[string]interface{}{"healthy": status == http.StatusOK}
+		if verbose {
+			body["checks"] = results
+		}
+		util.RespondJSON(w, status, body, util.EmptyHeader)
+	}
+}
+
 // SetupHealthzRoutes sets up the provided mux.Router to handle the
-// health check route.
-func SetupHealthzRoutes(router *mux.Router) {
+// health check routes. /livez runs the liveness checks and /readyz
+// runs the readiness checks. /healthz is the original check and
+// always succeeds.
+func SetupHealthzRoutes(router *mux.Router, liveChecks health.Checks, readyChecks health.Checks) {
 	router.HandleFunc("/healthz", healthCheck).Methods(http.MethodGet).Name("healthz")
+	router.HandleFunc("/livez", checkHandler(liveChecks)).Methods(http.MethodGet).Name("livez")
+	router.HandleFunc("/readyz", checkHandler(readyChecks)).Methods(http.MethodGet).Name("readyz")
 }
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// route identifies a named route and the variables that fill it in.
+type route struct {
+	name  string
+	pairs []string
+}
+
+// routeLinks builds a set of absolute links from named routes. See
+// util.RouteURL.
+func routeLinks(r *http.Request, router *mux.Router, routes map[string]route) (model.Links, error) {
+	links := model.Links{}
+	for rel, rt := range routes {
+		link, err := util.RouteURL(r, router, rt.name, rt.pairs...)
+		if err != nil {
+			return nil, err
+		}
+		links[rel] = link
+	}
+	return links, nil
+}
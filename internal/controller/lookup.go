@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// Lookup implements reverse lookups: which objects own a public
+// address or hostname.
+type Lookup struct {
+	client client.Client
+	router *mux.Router
+}
+
+// account searches one account.
+func (l *Lookup) account(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	// 404 if the account doesn't exist
+	if _, err := db.ReadAccount(r.Context(), l.client, vars["account"]); err != nil {
+		log.Error(err, "GET lookup failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	l.lookup(w, r, vars["account"], "account-lookup", []string{"account", vars["account"]})
+}
+
+// all searches every account. It's for operators so it must be
+// mounted on a router that authenticates its clients.
+func (l *Lookup) all(w http.ResponseWriter, r *http.Request) {
+	l.lookup(w, r, "", "admin-lookup", nil)
+}
+
+// lookup searches accountName, or every account if it's "", for the
+// objects that own the "ip" and "hostname" query parameters. selfName
+// and selfPairs identify the route that's being served.
+func (l *Lookup) lookup(w http.ResponseWriter, r *http.Request, accountName string, selfName string, selfPairs []string) {
+	log := log.FromContext(r.Context())
+	query := r.URL.Query()
+
+	result := model.NewLookup()
+	result.IP = query.Get("ip")
+	result.Hostname = query.Get("hostname")
+	if result.IP == "" && result.Hostname == "" {
+		err := fmt.Errorf("ip or hostname is required")
+		log.Error(err, "GET lookup failed")
+		util.RespondBad(w, err)
+		return
+	}
+
+	var err error
+	if result.Links, err = routeLinks(r, l.router, map[string]route{
+		"self": {selfName, selfPairs},
+	}); err != nil {
+		log.Error(err, "GET lookup failed")
+		util.RespondError(w, err)
+		return
+	}
+	// RouteURL doesn't know about the query so we add it back.
+	result.Links["self"] += "?" + query.Encode()
+
+	if result.IP != "" {
+		ip := net.ParseIP(result.IP)
+		if ip == nil {
+			err := fmt.Errorf("invalid ip %q", result.IP)
+			log.Error(err, "GET lookup failed")
+			util.RespondBad(w, err)
+			return
+		}
+		lbs, proxies, err := db.FindByAddress(r.Context(), l.client, accountName, ip)
+		if err != nil {
+			log.Error(err, "GET lookup failed")
+			util.RespondError(w, err)
+			return
+		}
+		for _, lb := range lbs {
+			account := db.AccountFromNamespace(lb.Namespace)
+			result.Matches = append(result.Matches, model.LookupMatch{
+				Kind:        "LoadBalancer",
+				Account:     account,
+				Name:        lb.Name,
+				DisplayName: lb.Spec.DisplayName,
+				Group:       lb.Labels[epicv1.OwningLBServiceGroupLabel],
+				Address:     lb.Spec.PublicAddress,
+				Clusters:    lb.Spec.UpstreamClusters,
+				Link:        l.link(r, "service", "account", account, "service", lb.Name),
+			})
+		}
+		for _, proxy := range proxies {
+			account := db.AccountFromNamespace(proxy.Namespace)
+			result.Matches = append(result.Matches, model.LookupMatch{
+				Kind:        "GWProxy",
+				Account:     account,
+				Name:        proxy.Name,
+				DisplayName: proxy.Spec.DisplayName,
+				Group:       proxy.Labels[epicv1.OwningLBServiceGroupLabel],
+				Address:     proxy.Spec.PublicAddress,
+				Clusters:    clientClusters(proxy.Spec.ClientRef),
+				ClientRef:   clientRef(proxy.Spec.ClientRef),
+				Link:        l.link(r, "proxy", "account", account, "proxy", proxy.Name),
+			})
+		}
+	}
+
+	if result.Hostname != "" {
+		routes, err := db.FindByHostname(r.Context(), l.client, accountName, result.Hostname)
+		if err != nil {
+			log.Error(err, "GET lookup failed")
+			util.RespondError(w, err)
+			return
+		}
+		for i := range routes {
+			rt := &routes[i]
+			account := db.AccountFromNamespace(rt.Namespace)
+			result.Matches = append(result.Matches, model.LookupMatch{
+				Kind:        "GWRoute",
+				Account:     account,
+				Name:        rt.Name,
+				DisplayName: rt.Spec.ClientRef.Name,
+				Hostnames:   routeHostnames(rt),
+				Clusters:    clientClusters(rt.Spec.ClientRef),
+				ClientRef:   clientRef(rt.Spec.ClientRef),
+				Link:        l.link(r, "route", "account", account, "route", rt.Name),
+			})
+		}
+	}
+
+	log.Info("GET lookup OK", "ip", result.IP, "hostname", result.Hostname, "count", len(result.Matches))
+	util.RespondJSON(w, http.StatusOK, result, util.EmptyHeader)
+}
+
+// link returns a link to a match, or "" if the route isn't served
+// here, e.g., on an admin-only listener.
+func (l *Lookup) link(r *http.Request, name string, pairs ...string) string {
+	link, err := util.RouteURL(r, l.router, name, pairs...)
+	if err != nil {
+		log.FromContext(r.Context()).V(1).Info("no link for lookup match", "route", name, "error", err.Error())
+		return ""
+	}
+	return link
+}
+
+// clientClusters returns the client cluster in ref as a list.
+func clientClusters(ref epicv1.ClientRef) []string {
+	if ref.ClusterID == "" {
+		return nil
+	}
+	return []string{ref.ClusterID}
+}
+
+// clientRef returns a pointer to a copy of ref, or nil if ref is
+// empty.
+func clientRef(ref epicv1.ClientRef) *epicv1.ClientRef {
+	if ref == (epicv1.ClientRef{}) {
+		return nil
+	}
+	return &ref
+}
+
+// SetupLookupRoutes sets up the provided mux.Router to handle the
+// per-account lookup route.
+func SetupLookupRoutes(router *mux.Router, client client.Client) {
+	lookup := &Lookup{client: client, router: router}
+	router.HandleFunc("/accounts/{account}/lookup", lookup.account).Methods(http.MethodGet).Name("account-lookup")
+}
+
+// SetupLookupAdminRoutes sets up the provided mux.Router to handle the
+// cross-account lookup route.
+func SetupLookupAdminRoutes(router *mux.Router, client client.Client) {
+	lookup := &Lookup{client: client, router: router}
+	router.HandleFunc("/lookup", lookup.all).Methods(http.MethodGet).Name("admin-lookup")
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/pool"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// PrefixAdmin implements the read-only ServicePrefix management API.
+// Like AccountAdmin it must be mounted on a router that
+// authenticates its clients.
+type PrefixAdmin struct {
+	client client.Client
+	router *mux.Router
+}
+
+func (p *PrefixAdmin) list(w http.ResponseWriter, r *http.Request) {
+	log := log.FromContext(r.Context())
+
+	prefixes, err := db.ListPrefixes(r.Context(), p.client)
+	if err != nil {
+		log.Error(err, "GET prefixes failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	list := model.NewPrefixList()
+	if list.Links, err = routeLinks(r, p.router, map[string]route{
+		"self": {"admin-prefixes", nil},
+	}); err != nil {
+		log.Error(err, "GET prefixes failed")
+		util.RespondError(w, err)
+		return
+	}
+	holders, err := db.ListHolders(r.Context(), p.client, prefixes)
+	if err != nil {
+		log.Error(err, "GET prefixes failed")
+		util.RespondError(w, err)
+		return
+	}
+	for _, prefix := range prefixes {
+		mprefix, err := p.view(r, prefix, holders[prefix.Name])
+		if err != nil {
+			log.Error(err, "GET prefixes failed")
+			util.RespondError(w, err)
+			return
+		}
+		model.Redact(mprefix)
+		list.Prefixes = append(list.Prefixes, *mprefix)
+	}
+
+	log.Info("GET prefixes OK", "count", len(list.Prefixes))
+	util.RespondJSON(w, http.StatusOK, list, util.EmptyHeader)
+}
+
+func (p *PrefixAdmin) show(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	prefix, err := db.ReadPrefix(r.Context(), p.client, vars["prefix"])
+	if err != nil {
+		log.Error(err, "GET prefix failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+	holders, err := db.ListPrefixHolders(r.Context(), p.client, prefix)
+	if err != nil {
+		log.Error(err, "GET prefix failed")
+		util.RespondError(w, err)
+		return
+	}
+	mprefix, err := p.view(r, *prefix, holders)
+	if err != nil {
+		log.Error(err, "GET prefix failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	log.Info("GET prefix OK")
+	respondObject(w, r, mprefix)
+}
+
+// view builds the wire representation of a ServicePrefix, including
+// its pool's usage by holders and the groups that allocate from it.
+func (p *PrefixAdmin) view(r *http.Request, prefix epicv1.ServicePrefix, holders []db.AddressHolder) (*model.Prefix, error) {
+	var err error
+
+	mprefix := model.NewPrefix()
+	mprefix.Prefix = prefix
+	if mprefix.Links, err = routeLinks(r, p.router, map[string]route{
+		"self":     {"admin-prefix", []string{"prefix", prefix.Name}},
+		"prefixes": {"admin-prefixes", nil},
+	}); err != nil {
+		return nil, err
+	}
+
+	mprefix.Pool = prefixUsage(prefix, holders)
+
+	groups, err := db.ListPrefixGroups(r.Context(), p.client, prefix.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		account := group.Labels[epicv1.OwningAccountLabel]
+		link, err := util.RouteURL(r, p.router, "admin-group", "account", account, "group", group.Name)
+		if err != nil {
+			return nil, err
+		}
+		mprefix.Groups = append(mprefix.Groups, model.GroupRef{Account: account, Name: group.Name, Link: link})
+	}
+
+	return &mprefix, nil
+}
+
+// prefixUsage measures how much of a ServicePrefix's pool holders
+// are using.
+func prefixUsage(prefix epicv1.ServicePrefix, holders []db.AddressHolder) model.PoolUsage {
+	addressPool, err := pool.Parse(prefix.Spec.Pool)
+	if err != nil {
+		// That's a problem with the prefix, not with this request.
+		return model.PoolUsage{Error: err.Error()}
+	}
+
+	usage := db.HoldersUsage(addressPool, holders)
+	return model.PoolUsage{
+		Ranges:    addressPool.Strings(),
+		Total:     json.Number(usage.Total.String()),
+		Allocated: json.Number(usage.Allocated.String()),
+		Free:      json.Number(usage.Free.String()),
+	}
+}
+
+// SetupPrefixAdminRoutes sets up the provided mux.Router to handle
+// the ServicePrefix management routes.
+func SetupPrefixAdminRoutes(router *mux.Router, client client.Client) {
+	admin := &PrefixAdmin{client: client, router: router}
+	router.HandleFunc("/prefixes/{prefix}", admin.show).Methods(http.MethodGet).Name("admin-prefix")
+	router.HandleFunc("/prefixes", admin.list).Methods(http.MethodGet).Name("admin-prefixes")
+}
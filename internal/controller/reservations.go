@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// Reservations implements the server side of the address reservation
+// web service protocol.
+type Reservations struct {
+	client   client.Client
+	router   *mux.Router
+	recorder record.EventRecorder
+}
+
+// ReservationUpdateRequest contains the data from a web service
+// request to extend a reservation. The reservation will expire TTL
+// from now; if TTL is zero then the default TTL is used.
+type ReservationUpdateRequest struct {
+	TTL metav1.Duration `json:"ttl"`
+}
+
+func (res *Reservations) list(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	// 404 if the account doesn't exist
+	if _, err := db.ReadAccount(r.Context(), res.client, vars["account"]); err != nil {
+		log.Error(err, "GET reservations failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	reservations, err := db.ListReservations(r.Context(), res.client, vars["account"])
+	if err != nil {
+		log.Error(err, "GET reservations failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	list := model.NewReservationList()
+	if list.Links, err = routeLinks(r, res.router, map[string]route{
+		"self":    {"account-reservations", []string{"account", vars["account"]}},
+		"account": {"account", []string{"account", vars["account"]}},
+	}); err != nil {
+		log.Error(err, "GET reservations failed")
+		util.RespondError(w, err)
+		return
+	}
+	for _, reservation := range reservations {
+		if reservation.Links, err = routeLinks(r, res.router, map[string]route{
+			"self": {"reservation", []string{"account", vars["account"], "reservation", reservation.Name}},
+		}); err != nil {
+			log.Error(err, "GET reservations failed")
+			util.RespondError(w, err)
+			return
+		}
+		list.Reservations = append(list.Reservations, reservation)
+	}
+
+	log.Info("GET reservations OK", "count", len(list.Reservations))
+	util.RespondJSON(w, http.StatusOK, list, util.EmptyHeader)
+}
+
+func (res *Reservations) show(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	reservation, err := db.ReadReservation(r.Context(), res.client, vars["account"], vars["reservation"])
+	if err != nil {
+		log.Error(err, "GET reservation failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+	routes := map[string]route{
+		"self":    {"reservation", []string{"account", vars["account"], "reservation", vars["reservation"]}},
+		"account": {"account", []string{"account", vars["account"]}},
+	}
+	if reservation.Group != "" {
+		routes["group"] = route{"group", []string{"account", vars["account"], "group", reservation.Group}}
+	}
+	if reservation.Links, err = routeLinks(r, res.router, routes); err != nil {
+		log.Error(err, "GET reservation failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	log.Info("GET reservation OK")
+	util.RespondJSON(w, http.StatusOK, reservation, util.EmptyHeader)
+}
+
+// put extends a reservation.
+func (res *Reservations) put(w http.ResponseWriter, r *http.Request) {
+	var body ReservationUpdateRequest
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Error(err, "PUT reservation failed")
+		util.RespondBad(w, err)
+		return
+	}
+
+	opts := db.CurrentReservationOptions()
+	ttl := body.TTL.Duration
+	if ttl == 0 {
+		ttl = opts.TTL
+	}
+	if ttl <= 0 || ttl > opts.MaxTTL {
+		err := fmt.Errorf("ttl must be positive and at most %s", opts.MaxTTL)
+		log.Error(err, "PUT reservation failed")
+		util.RespondBad(w, err)
+		return
+	}
+
+	if err := db.ExtendReservation(r.Context(), res.client, vars["account"], vars["reservation"], time.Now().Add(ttl)); err != nil {
+		log.Error(err, "PUT reservation failed")
+		if errors.IsNotFound(err) {
+			util.RespondNotFound(w, err)
+			return
+		}
+		util.RespondError(w, err)
+		return
+	}
+
+	selfURL, err := util.RouteURL(r, res.router, "reservation", "account", vars["account"], "reservation", vars["reservation"])
+	if err != nil {
+		log.Error(err, "PUT reservation failed")
+		util.RespondError(w, err)
+		return
+	}
+	log.Info("PUT reservation OK", "ttl", ttl)
+	recordEvent(res.recorder, r, accountRef(r.Context(), res.client, vars["account"]), corev1.EventTypeNormal, reasonUpdated, "Reservation %s extended by %s", vars["reservation"], ttl)
+	http.Redirect(w, r, selfURL, http.StatusFound)
+}
+
+// del releases a reservation so its address can be allocated again.
+func (res *Reservations) del(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+
+	if err := db.ReleaseReservation(r.Context(), res.client, vars["account"], vars["reservation"]); err != nil {
+		log.Error(err, "DELETE reservation failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	log.Info("DELETE reservation OK")
+	recordEvent(res.recorder, r, accountRef(r.Context(), res.client, vars["account"]), corev1.EventTypeNormal, reasonDeleted, "Reservation %s released", vars["reservation"])
+	util.RespondJSON(w, http.StatusOK, map[string]string{"message": "reservation released"}, util.EmptyHeader)
+}
+
+// holdRequest is what a DELETE request says about holding the
+// deleted object's address.
+type holdRequest struct {
+	hold bool
+	name string
+}
+
+// parseHold parses the "hold" and "reservation" query parameters of
+// a DELETE request. Addresses are held by default if holds are
+// enabled. "reservation" overrides the reservation's default name.
+func parseHold(r *http.Request) (holdRequest, error) {
+	req := holdRequest{hold: db.CurrentReservationOptions().TTL > 0}
+	query := r.URL.Query()
+	if param := query.Get("hold"); param != "" {
+		hold, err := strconv.ParseBool(param)
+		if err != nil {
+			return req, fmt.Errorf("invalid hold %q", param)
+		}
+		req.hold = req.hold && hold
+	}
+	if req.name = query.Get("reservation"); req.name != "" {
+		if errs := validation.IsConfigMapKey(req.name); len(errs) > 0 {
+			return req, fmt.Errorf("invalid reservation name %q: %s", req.name, strings.Join(errs, ", "))
+		}
+	}
+	return req, nil
+}
+
+// holdAddress reserves the address of an object that's about to be
+// deleted, so nothing can take the address between the delete and
+// the reservation. It returns false if there's nothing to reserve.
+// If the delete fails the caller should release the reservation.
+func holdAddress(ctx context.Context, cl client.Client, accountName string, reservation model.Reservation) (bool, error) {
+	if reservation.Address == "" {
+		return false, nil
+	}
+	now := time.Now()
+	reservation.Account = accountName
+	reservation.Created = metav1.NewTime(now)
+	reservation.Expires = metav1.NewTime(now.Add(db.CurrentReservationOptions().TTL))
+
+	if err := db.Reserve(ctx, cl, accountName, reservation); err != nil {
+		return false, err
+	}
+	log.FromContext(ctx).Info("address reserved", "address", reservation.Address, "reservation", reservation.Name, "expires", reservation.Expires)
+	return true, nil
+}
+
+// reservationLink returns a link to a reservation, or "" if it
+// can't be built. A failure is logged but isn't fatal since the
+// reservation exists either way.
+func reservationLink(r *http.Request, router *mux.Router, accountName string, name string) string {
+	link, err := util.RouteURL(r, router, "reservation", "account", accountName, "reservation", name)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "building reservation link failed", "reservation", name)
+		return ""
+	}
+	return link
+}
+
+// proxyReservation describes the reservation that a deleted proxy
+// leaves.
+func proxyReservation(proxy *epicv1.GWProxy, name string) model.Reservation {
+	if name == "" {
+		name = db.ProxyReservationName(proxy.Spec.ClientRef)
+	}
+	ref := proxy.Spec.ClientRef
+	return model.Reservation{
+		Name:      name,
+		Address:   proxy.Spec.PublicAddress,
+		Prefix:    proxy.Labels[epicv1.OwningServicePrefixLabel],
+		Group:     proxy.Labels[epicv1.OwningLBServiceGroupLabel],
+		Kind:      "GWProxy",
+		Object:    proxy.Name,
+		ClientRef: &ref,
+	}
+}
+
+// serviceReservation describes the reservation that a deleted
+// service leaves.
+func serviceReservation(service *epicv1.LoadBalancer, name string) model.Reservation {
+	if name == "" {
+		name = db.ServiceReservationName(service.Name)
+	}
+	return model.Reservation{
+		Name:    name,
+		Address: service.Spec.PublicAddress,
+		Prefix:  service.Labels[epicv1.OwningServicePrefixLabel],
+		Group:   service.Labels[epicv1.OwningLBServiceGroupLabel],
+		Kind:    "LoadBalancer",
+		Object:  service.Name,
+	}
+}
+
+// releaseClaimed releases the reservation whose address a new object
+// took, or that held the address of an object that we then failed to
+// delete. A failure is logged but isn't fatal since the reservation
+// will expire.
+func releaseClaimed(ctx context.Context, cl client.Client, accountName string, name string) {
+	if err := db.ReleaseReservation(ctx, cl, accountName, name); err != nil {
+		log.FromContext(ctx).Error(err, "releasing claimed reservation failed", "reservation", name)
+	}
+}
+
+// SetupReservationRoutes sets up the provided mux.Router to handle
+// the address reservation routes.
+func SetupReservationRoutes(router *mux.Router, client client.Client, recorder record.EventRecorder) {
+	res := &Reservations{client: client, router: router, recorder: recorder}
+	router.HandleFunc("/accounts/{account}/reservations/{reservation}", res.show).Methods(http.MethodGet).Name("reservation")
+	router.HandleFunc("/accounts/{account}/reservations/{reservation}", res.put).Methods(http.MethodPut)
+	router.HandleFunc("/accounts/{account}/reservations/{reservation}", res.del).Methods(http.MethodDelete)
+	router.HandleFunc("/accounts/{account}/reservations", res.list).Methods(http.MethodGet).Name("account-reservations")
+}
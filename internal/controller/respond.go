@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// respondObject sends obj to the client with its Kubernetes metadata
+// redacted and its ETag in the header. If the client sent a matching
+// If-None-Match header then it already has this version so we send
+// 304 with no body. If the request has a "fields" query parameter
+// then only those fields are sent. Responses with embedded objects
+// don't have an ETag since it would cover only obj.
+func respondObject(w http.ResponseWriter, r *http.Request, obj model.Object) {
+	query := r.URL.Query()
+
+	etag := model.Redact(obj)
+	if _, embedded := query["embed"]; embedded {
+		etag = ""
+	}
+
+	headers := map[string]string{}
+	if etag != "" {
+		for _, match := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+			match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+			if match == etag || match == "*" {
+				w.Header().Set("ETag", etag)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		headers["ETag"] = etag
+	}
+
+	var payload interface{} = obj
+	if fields := fieldsParam(r); len(fields) > 0 {
+		selected, err := model.SelectFields(obj, fields)
+		if err != nil {
+			util.RespondBad(w, err)
+			return
+		}
+		payload = selected
+	}
+
+	util.RespondJSON(w, http.StatusOK, payload, headers)
+}
+
+// fieldsParam parses the request's "fields" query parameter, e.g.,
+// "?fields=link.self,proxy.spec.public-address".
+func fieldsParam(r *http.Request) []string {
+	fields := []string{}
+	for _, value := range r.URL.Query()["fields"] {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
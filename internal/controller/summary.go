@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+// Anomaly codes.
+const (
+	anomalyNoAddress      = "no-address"
+	anomalyMissingGroup   = "missing-group"
+	anomalyMissingParent  = "missing-parent"
+	anomalyMissingBackend = "missing-backend"
+	anomalyUnreferenced   = "unreferenced"
+	anomalyNoEndpoints    = "no-endpoints"
+	anomalyOrphanEndpoint = "orphan-endpoint"
+)
+
+// showSummary responds with an inventory of everything in an
+// account, and flags anything that looks wrong.
+func (g *EPIC) showSummary(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log := log.FromContext(r.Context())
+	account := vars["account"]
+	ctx := r.Context()
+
+	// 404 if the account doesn't exist
+	if _, err := db.ReadAccount(ctx, g.client, account); err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondNotFound(w, err)
+		return
+	}
+
+	groups, err := db.ListGroups(ctx, g.client, account)
+	if err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondError(w, err)
+		return
+	}
+	services, err := db.ListServices(ctx, g.client, account)
+	if err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondError(w, err)
+		return
+	}
+	proxies, err := db.ListProxies(ctx, g.client, account)
+	if err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondError(w, err)
+		return
+	}
+	routes, err := db.ListRoutes(ctx, g.client, account)
+	if err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondError(w, err)
+		return
+	}
+	slices, err := db.ListSlices(ctx, g.client, account)
+	if err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondError(w, err)
+		return
+	}
+	endpoints, err := db.ListEndpoints(ctx, g.client, account)
+	if err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	summary := model.NewSummary(account)
+	if summary.Links, err = routeLinks(r, g.router, map[string]route{
+		"self":    {"account-summary", []string{"account", account}},
+		"account": {"account", []string{"account", account}},
+	}); err != nil {
+		log.Error(err, "GET summary failed")
+		util.RespondError(w, err)
+		return
+	}
+
+	// link returns the URL of a named route, or "" if it can't be
+	// built. A summary with a missing link is more useful than no
+	// summary.
+	link := func(name string, pairs ...string) string {
+		url, err := util.RouteURL(r, g.router, name, pairs...)
+		if err != nil {
+			log.Info("summary link failed", "route", name, "error", err.Error())
+			return ""
+		}
+		return url
+	}
+	anomaly := func(kind string, name string, code string, format string, args ...interface{}) {
+		summary.Anomalies = append(summary.Anomalies, model.Anomaly{Kind: kind, Name: name, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	groupNames := map[string]bool{}
+	for _, group := range groups {
+		groupNames[group.Group.Name] = true
+		summary.Groups = append(summary.Groups, model.SummaryItem{
+			Name: group.Group.Name,
+			Link: link("group", "account", account, "group", group.Group.Name),
+		})
+	}
+
+	serviceEndpoints := map[string]int{}
+	for _, ep := range endpoints {
+		serviceEndpoints[ep.Endpoint.Labels[epicv1.OwningLoadBalancerLabel]]++
+	}
+
+	for _, service := range services {
+		lb := service.Service
+		group := lb.Labels[epicv1.OwningLBServiceGroupLabel]
+		summary.Services = append(summary.Services, model.SummaryItem{
+			Name:        lb.Name,
+			DisplayName: lb.Spec.DisplayName,
+			Group:       group,
+			Address:     lb.Spec.PublicAddress,
+			Link:        link("service", "account", account, "service", lb.Name),
+		})
+		if lb.Spec.PublicAddress == "" {
+			anomaly("LoadBalancer", lb.Name, anomalyNoAddress, "LoadBalancer has no public address")
+		}
+		if !groupNames[group] {
+			anomaly("LoadBalancer", lb.Name, anomalyMissingGroup, "LoadBalancer's group %q doesn't exist", group)
+		}
+		if len(lb.Spec.UpstreamClusters) > 0 && serviceEndpoints[lb.Name] == 0 {
+			anomaly("LoadBalancer", lb.Name, anomalyNoEndpoints, "LoadBalancer has %d upstream clusters but no endpoints", len(lb.Spec.UpstreamClusters))
+		}
+	}
+
+	proxyNames := map[string]bool{}
+	for _, proxy := range proxies {
+		gwp := proxy.Proxy
+		proxyNames[gwp.Name] = true
+		group := gwp.Labels[epicv1.OwningLBServiceGroupLabel]
+		summary.Proxies = append(summary.Proxies, model.SummaryItem{
+			Name:        gwp.Name,
+			DisplayName: gwp.Spec.DisplayName,
+			Group:       group,
+			Address:     gwp.Spec.PublicAddress,
+			Cluster:     gwp.Spec.ClientRef.ClusterID,
+			Link:        link("proxy", "account", account, "proxy", gwp.Name),
+		})
+		if gwp.Spec.PublicAddress == "" {
+			anomaly("GWProxy", gwp.Name, anomalyNoAddress, "GWProxy has no public address")
+		}
+		if !groupNames[group] {
+			anomaly("GWProxy", gwp.Name, anomalyMissingGroup, "GWProxy's group %q doesn't exist", group)
+		}
+	}
+
+	sliceNames := map[string]bool{}
+	for _, slice := range slices {
+		sliceNames[slice.Slice.Name] = true
+	}
+
+	referencedSlices := map[string]bool{}
+	for _, rt := range routes {
+		gwr := rt.Route
+		item := model.SummaryItem{
+			Name:        gwr.Name,
+			DisplayName: gwr.Spec.ClientRef.Name,
+			Cluster:     gwr.Spec.ClientRef.ClusterID,
+			Link:        link("route", "account", account, "route", gwr.Name),
+		}
+		if gwr.Spec.HTTP != nil {
+			for _, hostname := range gwr.Spec.HTTP.Hostnames {
+				item.Hostnames = append(item.Hostnames, string(hostname))
+			}
+		}
+		summary.Routes = append(summary.Routes, item)
+
+		for _, parent := range routeParents(&gwr) {
+			if !proxyNames[parent] {
+				anomaly("GWRoute", gwr.Name, anomalyMissingParent, "GWRoute's parent GWProxy %q doesn't exist", parent)
+			}
+		}
+		for _, backend := range routeBackends(&gwr) {
+			referencedSlices[backend] = true
+			if !sliceNames[backend] {
+				anomaly("GWRoute", gwr.Name, anomalyMissingBackend, "GWRoute's backend GWEndpointSlice %q doesn't exist", backend)
+			}
+		}
+	}
+
+	for _, slice := range slices {
+		gws := slice.Slice
+		summary.Slices = append(summary.Slices, model.SummaryItem{
+			Name:        gws.Name,
+			DisplayName: gws.Spec.ClientRef.Name,
+			Cluster:     gws.Spec.ClientRef.ClusterID,
+			Link:        link("slice", "account", account, "slice", gws.Name),
+		})
+		if !referencedSlices[gws.Name] {
+			anomaly("GWEndpointSlice", gws.Name, anomalyUnreferenced, "GWEndpointSlice isn't a backend of any GWRoute")
+		}
+	}
+
+	serviceNames := map[string]bool{}
+	for _, service := range services {
+		serviceNames[service.Service.Name] = true
+	}
+	for _, ep := range endpoints {
+		rep := ep.Endpoint
+		service := rep.Labels[epicv1.OwningLoadBalancerLabel]
+		item := model.SummaryItem{
+			Name:    rep.Name,
+			Address: rep.Spec.Address,
+			Cluster: rep.Spec.Cluster,
+		}
+		if serviceNames[service] {
+			item.Link = link("endpoint", "account", account, "service", service, "endpoint", rep.Name)
+		} else {
+			anomaly("RemoteEndpoint", rep.Name, anomalyOrphanEndpoint, "RemoteEndpoint's LoadBalancer %q doesn't exist", service)
+		}
+		summary.Endpoints = append(summary.Endpoints, item)
+	}
+
+	summary.Counts = map[string]int{
+		"groups":    len(summary.Groups),
+		"services":  len(summary.Services),
+		"proxies":   len(summary.Proxies),
+		"routes":    len(summary.Routes),
+		"slices":    len(summary.Slices),
+		"endpoints": len(summary.Endpoints),
+		"anomalies": len(summary.Anomalies),
+	}
+	sort.SliceStable(summary.Anomalies, func(i, j int) bool {
+		if summary.Anomalies[i].Kind != summary.Anomalies[j].Kind {
+			return summary.Anomalies[i].Kind < summary.Anomalies[j].Kind
+		}
+		return summary.Anomalies[i].Name < summary.Anomalies[j].Name
+	})
+
+	log.Info("GET summary OK", "anomalies", len(summary.Anomalies))
+	util.RespondJSON(w, http.StatusOK, summary, util.EmptyHeader)
+}
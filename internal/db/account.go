@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/tracing"
+)
+
+// AccountRoleBindingName is the name of the RoleBinding that
+// CreateAccount adds to each account namespace.
+const AccountRoleBindingName = "epic-account"
+
+var (
+	// ErrClusterRoleNotAllowed is returned when an account would be
+	// given a ClusterRole other than the configured one.
+	ErrClusterRoleNotAllowed = fmt.Errorf("cluster role is not the configured account cluster role")
+
+	// ErrNotAccountNamespace is returned when an account's namespace
+	// doesn't belong to the account, so it can't be deleted with it.
+	ErrNotAccountNamespace = fmt.Errorf("namespace does not belong to the account")
+)
+
+var (
+	accountRoleMu      sync.RWMutex
+	accountClusterRole string
+)
+
+// SetAccountClusterRole sets the only ClusterRole that CreateAccount
+// binds in account namespaces. It should match the role that the web
+// service's own RBAC lets it bind. If name is empty then accounts
+// don't get a RoleBinding. It should be called once at startup.
+func SetAccountClusterRole(name string) {
+	accountRoleMu.Lock()
+	defer accountRoleMu.Unlock()
+	accountClusterRole = name
+}
+
+// AccountClusterRole returns the ClusterRole that CreateAccount
+// binds.
+func AccountClusterRole() string {
+	accountRoleMu.RLock()
+	defer accountRoleMu.RUnlock()
+	return accountClusterRole
+}
+
+// AccountSetup describes a new account and the objects that come
+// with it.
+type AccountSetup struct {
+	Account epicv1.Account
+	Groups  []model.GroupTemplate
+
+	// ClusterRole, if not empty, is bound in the account's namespace
+	// to the Kubernetes group "epic:account:<name>". It must be the
+	// role set by SetAccountClusterRole().
+	ClusterRole string
+}
+
+// AccountGroup is the Kubernetes group whose members can act on an
+// account's objects, if the account has a RoleBinding.
+func AccountGroup(accountName string) string {
+	return "epic:account:" + accountName
+}
+
+// ListAccounts lists every account.
+func ListAccounts(ctx context.Context, cl client.Client) (_ []model.Account, err error) {
+	ctx, span := startSpan(ctx, "db.ListAccounts", "", "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.AccountList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &list) }); err != nil {
+		return nil, err
+	}
+
+	accounts := make([]model.Account, 0, len(list.Items))
+	for _, item := range list.Items {
+		maccount := model.NewAccount()
+		maccount.Account = item
+		accounts = append(accounts, maccount)
+	}
+	return accounts, nil
+}
+
+// CreateAccount creates an account's namespace, the Account itself,
+// its groups and its RoleBinding. It returns an AlreadyExists error
+// if the Account exists, and ErrClusterRoleNotAllowed if setup asks
+// for a ClusterRole other than the configured one. If an earlier
+// attempt failed part way then the objects that it created are
+// reused, so it's safe to retry.
+func CreateAccount(ctx context.Context, cl client.Client, setup AccountSetup) (err error) {
+	accountName := setup.Account.Name
+	ctx, span := startSpan(ctx, "db.CreateAccount", accountName, accountName)
+	defer func() { tracing.End(span, err) }()
+
+	if setup.ClusterRole != "" && setup.ClusterRole != AccountClusterRole() {
+		return ErrClusterRoleNotAllowed
+	}
+
+	namespace := epicv1.AccountNamespace(accountName)
+	owner := map[string]string{epicv1.OwningAccountLabel: accountName}
+
+	// The Account goes first so a duplicate request doesn't touch
+	// anything else, but it needs its namespace.
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: owner}}
+	if err := createIfMissing(ctx, cl, &ns); err != nil {
+		return fmt.Errorf("creating namespace %s: %w", namespace, err)
+	}
+
+	setup.Account.Namespace = namespace
+	if err := withRetries(ctx, func() error { return cl.Create(ctx, &setup.Account) }); err != nil {
+		return err
+	}
+
+	for _, template := range setup.Groups {
+		group := newGroup(accountName, template)
+		if err := createIfMissing(ctx, cl, &group); err != nil {
+			return fmt.Errorf("creating group %s: %w", template.Name, err)
+		}
+	}
+
+	if setup.ClusterRole != "" {
+		binding := rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: AccountRoleBindingName, Labels: owner},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: setup.ClusterRole},
+			Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: AccountGroup(accountName)}},
+		}
+		if err := createIfMissing(ctx, cl, &binding); err != nil {
+			return fmt.Errorf("creating role binding: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteAccount deletes an account by deleting its namespace, which
+// cascades to everything in it. It returns the number of each kind
+// of object that's being deleted. If dryRun is true it only counts.
+// Kubernetes finishes deleting the namespace in the background. It
+// returns ErrNotAccountNamespace if the namespace doesn't carry the
+// account's label, since then it's not ours to delete.
+func DeleteAccount(ctx context.Context, cl client.Client, accountName string, dryRun bool) (_ map[string]int, err error) {
+	ctx, span := startSpan(ctx, "db.DeleteAccount", accountName, accountName)
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := ReadAccount(ctx, cl, accountName); err != nil {
+		return nil, err
+	}
+
+	ns := corev1.Namespace{}
+	if err := withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Name: epicv1.AccountNamespace(accountName)}, &ns)
+	}); err != nil {
+		return nil, err
+	}
+	if !isAccountNamespace(&ns, accountName) {
+		log.FromContext(ctx).Info("not deleting namespace without the account label", "namespace", ns.Name, "labels", ns.Labels)
+		return nil, ErrNotAccountNamespace
+	}
+
+	report := map[string]int{"Account": 1}
+	for kind, list := range map[string]client.ObjectList{
+		"LBServiceGroup":  &epicv1.LBServiceGroupList{},
+		"LoadBalancer":    &epicv1.LoadBalancerList{},
+		"RemoteEndpoint":  &epicv1.RemoteEndpointList{},
+		"GWProxy":         &epicv1.GWProxyList{},
+		"GWRoute":         &epicv1.GWRouteList{},
+		"GWEndpointSlice": &epicv1.GWEndpointSliceList{},
+	} {
+		if err := listInAccount(ctx, cl, accountName, list, nil); err != nil {
+			return nil, err
+		}
+		report[kind] = listLen(list)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err := withRetries(ctx, func() error {
+		return cl.Delete(ctx, &ns, client.Preconditions{UID: &ns.UID})
+	}); err != nil {
+		if errors.IsNotFound(err) {
+			log.FromContext(ctx).Info("namespace not found, ignoring since it must be deleted", "namespace", ns.Name)
+			return report, nil
+		}
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// isAccountNamespace indicates whether ns is accountName's namespace:
+// it has the account namespace prefix and the account's label.
+func isAccountNamespace(ns *corev1.Namespace, accountName string) bool {
+	return strings.HasPrefix(ns.Name, epicv1.AccountNamespace("")) &&
+		ns.Name == epicv1.AccountNamespace(accountName) &&
+		ns.Labels[epicv1.OwningAccountLabel] == accountName
+}
+
+// createIfMissing creates obj, and succeeds if it already exists.
+func createIfMissing(ctx context.Context, cl client.Client, obj client.Object) error {
+	return withRetries(ctx, func() error {
+		if err := cl.Create(ctx, obj); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// pollInterval is how often we check the cache while waiting for
+	// it to catch up with a write.
+	pollInterval = 20 * time.Millisecond
+
+	// maxWriteAge limits how long we remember a write. If nobody reads
+	// the object within this time then we forget about it.
+	maxWriteAge = 5 * time.Minute
+)
+
+// write is what we remember about an object that we wrote.
+type write struct {
+	resourceVersion string
+	deleted         bool
+	when            time.Time
+}
+
+// consistentClient reads from the informer cache but guarantees that
+// a read that follows a write through this client sees that write
+// (or something newer). After each write it remembers the
+// resourceVersion that the API server returned, and the next read of
+// that object waits until the cache has caught up. If the cache
+// doesn't catch up within the timeout then the read goes directly to
+// the API server. Reads of objects that we haven't written go to the
+// cache, and to the API server if the cache doesn't have them, since
+// another replica might have just created them.
+type consistentClient struct {
+	client.Client
+
+	apiReader client.Reader
+	timeout   time.Duration
+
+	mu     sync.Mutex
+	writes map[string]write
+}
+
+// NewConsistentClient wraps cl, which should be the manager's
+// cache-backed client, so reads see the effects of earlier writes.
+// apiReader is an uncached reader, i.e., the manager's APIReader,
+// and timeout limits how long a read waits for the cache.
+func NewConsistentClient(cl client.Client, apiReader client.Reader, timeout time.Duration) client.Client {
+	return &consistentClient{
+		Client:    cl,
+		apiReader: apiReader,
+		timeout:   timeout,
+		writes:    map[string]write{},
+	}
+}
+
+func (c *consistentClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	k := objKey(obj, key.Namespace, key.Name)
+
+	c.mu.Lock()
+	w, pending := c.writes[k]
+	c.mu.Unlock()
+
+	if !pending {
+		err := c.Client.Get(ctx, key, obj)
+		if errors.IsNotFound(err) {
+			return c.apiReader.Get(ctx, key, obj)
+		}
+		return err
+	}
+
+	// Wait for the cache to catch up with our write.
+	var err error
+	waitCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	pollErr := wait.PollImmediateUntil(pollInterval, func() (bool, error) {
+		err = c.Client.Get(waitCtx, key, obj)
+		if w.deleted {
+			// Objects with finalizers stick around after they're deleted
+			// so it's enough for the cache to see that they're going.
+			return errors.IsNotFound(err) || err == nil && obj.GetDeletionTimestamp() != nil, nil
+		}
+		if err != nil {
+			return false, nil
+		}
+		return obj.GetResourceVersion() == w.resourceVersion, nil
+	}, waitCtx.Done())
+	if pollErr == nil {
+		c.forget(k, w)
+		return err
+	}
+
+	// The cache didn't catch up in time so go to the source.
+	log.FromContext(ctx).Info("cache lagging, reading from API server", "object", k, "resourceVersion", w.resourceVersion)
+	return c.apiReader.Get(ctx, key, obj)
+}
+
+func (c *consistentClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.remember(obj, false)
+	return nil
+}
+
+func (c *consistentClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.remember(obj, false)
+	return nil
+}
+
+func (c *consistentClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	c.remember(obj, false)
+	return nil
+}
+
+func (c *consistentClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.remember(obj, true)
+	return nil
+}
+
+// remember records a write to obj.
+func (c *consistentClient) remember(obj client.Object, deleted bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writes[objKey(obj, obj.GetNamespace(), obj.GetName())] = write{
+		resourceVersion: obj.GetResourceVersion(),
+		deleted:         deleted,
+		when:            now,
+	}
+
+	// Prune writes that nobody read.
+	for k, w := range c.writes {
+		if now.Sub(w.when) > maxWriteAge {
+			delete(c.writes, k)
+		}
+	}
+}
+
+// forget stops tracking a write once the cache has caught up. If
+// another write to the object happened in the meantime then we keep
+// tracking that one.
+func (c *consistentClient) forget(k string, w write) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writes[k] == w {
+		delete(c.writes, k)
+	}
+}
+
+// objKey identifies an object by its Go type, namespace and name.
+func objKey(obj client.Object, namespace string, name string) string {
+	return fmt.Sprintf("%T/%s/%s", obj, namespace, name)
+}
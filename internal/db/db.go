@@ -2,90 +2,91 @@ package db
 
 import (
 	"context"
-	"fmt"
-	"time"
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/tracing"
 )
 
+// startSpan starts a tracing span for a db-layer call.
+func startSpan(ctx context.Context, name string, accountName string, objName string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, attribute.String("epic.account", accountName), attribute.String("epic.name", objName))
+}
+
 // ReadAccount reads one account from the cluster.
-func ReadAccount(ctx context.Context, cl client.Client, accountName string) (*model.Account, error) {
+func ReadAccount(ctx context.Context, cl client.Client, accountName string) (_ *model.Account, err error) {
+	ctx, span := startSpan(ctx, "db.ReadAccount", accountName, accountName)
+	defer func() { tracing.End(span, err) }()
+
 	maccount := model.NewAccount()
-	return &maccount, cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: accountName}, &maccount.Account)
+	return &maccount, withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: accountName}, &maccount.Account)
+	})
 }
 
 // ReadGroup reads one service group from the cluster.
-func ReadGroup(ctx context.Context, cl client.Client, accountName string, name string) (*model.Group, error) {
+func ReadGroup(ctx context.Context, cl client.Client, accountName string, name string) (_ *model.Group, err error) {
+	ctx, span := startSpan(ctx, "db.ReadGroup", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
 	mgroup := model.NewGroup()
-	return &mgroup, cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mgroup.Group)
+	return &mgroup, withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mgroup.Group)
+	})
 }
 
 // ReadService reads one load balancer service from the cluster.
-func ReadService(ctx context.Context, cl client.Client, accountName string, name string) (*model.Service, error) {
-	var err error
+func ReadService(ctx context.Context, cl client.Client, accountName string, name string) (_ *model.Service, err error) {
+	ctx, span := startSpan(ctx, "db.ReadService", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
 	mservice := model.NewService()
-	tries := 2
-	for err = fmt.Errorf(""); err != nil && tries > 0; tries-- {
-		err = cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mservice.Service)
-		if err != nil {
-			fmt.Printf("problem reading service %s/%s: %s\n", accountName, name, err)
-			if tries > 1 {
-				time.Sleep(1 * time.Second)
-			}
-		}
-	}
-	return &mservice, err
+	return &mservice, withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mservice.Service)
+	})
 }
 
 // ReadProxy reads one GWProxy resource from the cluster.
-func ReadProxy(ctx context.Context, cl client.Client, accountName string, name string) (*model.Proxy, error) {
-	var err error
+func ReadProxy(ctx context.Context, cl client.Client, accountName string, name string) (_ *model.Proxy, err error) {
+	ctx, span := startSpan(ctx, "db.ReadProxy", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
 	mproxy := model.NewProxy()
-	tries := 2
-	for err = fmt.Errorf(""); err != nil && tries > 0; tries-- {
-		err = cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mproxy.Proxy)
-		if err != nil {
-			fmt.Printf("problem reading proxy %s/%s: %s\n", accountName, name, err)
-			if tries > 1 {
-				time.Sleep(1 * time.Second)
-			}
-		}
-	}
-	return &mproxy, err
+	return &mproxy, withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mproxy.Proxy)
+	})
 }
 
 // ReadEndpoint reads one service endpoint from the cluster.
-func ReadEndpoint(ctx context.Context, cl client.Client, accountName string, name string) (*model.Endpoint, error) {
-	var err error
+func ReadEndpoint(ctx context.Context, cl client.Client, accountName string, name string) (_ *model.Endpoint, err error) {
+	ctx, span := startSpan(ctx, "db.ReadEndpoint", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
 	mendpoint := model.NewEndpoint()
-	tries := 2
-	for err = fmt.Errorf(""); err != nil && tries > 0; tries-- {
-		err = cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mendpoint.Endpoint)
-		if err != nil {
-			fmt.Printf("problem reading endpoint %s/%s: %#v\n", accountName, name, err)
-			if tries > 1 {
-				time.Sleep(1 * time.Second)
-			}
-		}
-	}
-	return &mendpoint, err
+	return &mendpoint, withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mendpoint.Endpoint)
+	})
 }
 
 // DeleteService deletes the specified load balancer.
-func DeleteService(ctx context.Context, cl client.Client, accountName string, name string) error {
+func DeleteService(ctx context.Context, cl client.Client, accountName string, name string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteService", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
 	service, err := ReadService(ctx, cl, accountName, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found. Not great, but the client wanted
 			// the object gone and it's gone.
-			fmt.Printf("%s/%s not found. Ignoring since object must be deleted\n", accountName, name)
+			log.FromContext(ctx).Info("not found, ignoring since object must be deleted", "name", name)
 			return nil
 		}
 		return err
@@ -99,8 +100,11 @@ func DeleteService(ctx context.Context, cl client.Client, accountName string, na
 }
 
 // DeleteProxy deletes the specified GWProxy.
-func DeleteProxy(ctx context.Context, cl client.Client, accountName string, name string) error {
-	err := cl.Delete(
+func DeleteProxy(ctx context.Context, cl client.Client, accountName string, name string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteProxy", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	err = cl.Delete(
 		ctx,
 		&epicv1.GWProxy{
 			ObjectMeta: metav1.ObjectMeta{
@@ -113,7 +117,7 @@ func DeleteProxy(ctx context.Context, cl client.Client, accountName string, name
 		if errors.IsNotFound(err) {
 			// Request object not found. Not great, but the client wanted
 			// the object gone and it's gone.
-			fmt.Printf("%s/%s not found. Ignoring since object must be deleted\n", accountName, name)
+			log.FromContext(ctx).Info("not found, ignoring since object must be deleted", "name", name)
 			return nil
 		}
 		return err
@@ -122,18 +126,21 @@ func DeleteProxy(ctx context.Context, cl client.Client, accountName string, name
 	return nil
 }
 
-func DeleteCluster(ctx context.Context, cl client.Client, accountName string, serviceName string, cluster string) error {
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		service, err := ReadService(ctx, cl, accountName, serviceName)
-		if err != nil {
+func DeleteCluster(ctx context.Context, cl client.Client, accountName string, serviceName string, cluster string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteCluster", accountName, serviceName)
+	defer func() { tracing.End(span, err) }()
+
+	return withRetries(ctx, func() error {
+		service := epicv1.LoadBalancer{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: serviceName}, &service); err != nil {
 			return err
 		}
 
-		if err := service.Service.RemoveUpstream(cluster); err != nil {
+		if err := service.RemoveUpstream(cluster); err != nil {
 			return err
 		}
 
-		if err := cl.Update(ctx, &service.Service); err != nil {
+		if err := cl.Update(ctx, &service); err != nil {
 			return err
 		}
 
@@ -141,8 +148,11 @@ func DeleteCluster(ctx context.Context, cl client.Client, accountName string, se
 	})
 }
 
-func DeleteClusterReps(ctx context.Context, cl client.Client, accountName string, serviceName string, cluster string) error {
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+func DeleteClusterReps(ctx context.Context, cl client.Client, accountName string, serviceName string, cluster string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteClusterReps", accountName, serviceName)
+	defer func() { tracing.End(span, err) }()
+
+	return withRetries(ctx, func() error {
 		// Delete the endpoints that belong to this cluster
 		return cl.DeleteAllOf(
 			ctx,
@@ -153,13 +163,16 @@ func DeleteClusterReps(ctx context.Context, cl client.Client, accountName string
 }
 
 // DeleteEndpoint deletes the specified load balancer.
-func DeleteEndpoint(ctx context.Context, cl client.Client, accountName string, repName string) error {
+func DeleteEndpoint(ctx context.Context, cl client.Client, accountName string, repName string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteEndpoint", accountName, repName)
+	defer func() { tracing.End(span, err) }()
+
 	endpoint, err := ReadEndpoint(ctx, cl, accountName, repName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found. Not great, but the client wanted
 			// the object gone and it's gone.
-			fmt.Printf("%s/%s not found. Ignoring since object must be deleted\n", accountName, repName)
+			log.FromContext(ctx).Info("not found, ignoring since object must be deleted", "name", repName)
 			return nil
 		}
 		return err
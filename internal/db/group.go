@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/tracing"
+)
+
+// ErrGroupInUse is returned when a group can't be changed or deleted
+// because it has services or proxies.
+var ErrGroupInUse = fmt.Errorf("group has services or proxies")
+
+// CreateGroup creates an LBServiceGroup from a template.
+func CreateGroup(ctx context.Context, cl client.Client, accountName string, template model.GroupTemplate) (err error) {
+	ctx, span := startSpan(ctx, "db.CreateGroup", accountName, template.Name)
+	defer func() { tracing.End(span, err) }()
+
+	group := newGroup(accountName, template)
+	return withRetries(ctx, func() error { return cl.Create(ctx, &group) })
+}
+
+// UpdateGroup changes a group's service prefix and sharing. Neither
+// can change while the group has services or proxies since they'd
+// be left with addresses from the wrong pool, or with the wrong
+// names, so in that case it returns ErrGroupInUse.
+func UpdateGroup(ctx context.Context, cl client.Client, accountName string, template model.GroupTemplate) (err error) {
+	ctx, span := startSpan(ctx, "db.UpdateGroup", accountName, template.Name)
+	defer func() { tracing.End(span, err) }()
+
+	return withRetries(ctx, func() error {
+		group := &epicv1.LBServiceGroup{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: template.Name}, group); err != nil {
+			return err
+		}
+
+		if group.Labels[epicv1.OwningServicePrefixLabel] == template.ServicePrefix && group.Spec.CanBeShared == template.CanBeShared {
+			return nil
+		}
+		inUse, err := groupInUse(ctx, cl, accountName, template.Name)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrGroupInUse
+		}
+
+		if group.Labels == nil {
+			group.Labels = map[string]string{}
+		}
+		group.Labels[epicv1.OwningServicePrefixLabel] = template.ServicePrefix
+		group.Spec.CanBeShared = template.CanBeShared
+		return cl.Update(ctx, group)
+	})
+}
+
+// DeleteGroup deletes a group. It returns ErrGroupInUse if the group
+// has services or proxies.
+func DeleteGroup(ctx context.Context, cl client.Client, accountName string, name string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteGroup", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := ReadGroup(ctx, cl, accountName, name); err != nil {
+		return err
+	}
+	var inUse bool
+	if err := withRetries(ctx, func() (err error) {
+		inUse, err = groupInUse(ctx, cl, accountName, name)
+		return err
+	}); err != nil {
+		return err
+	}
+	if inUse {
+		return ErrGroupInUse
+	}
+
+	return withRetries(ctx, func() error {
+		return cl.Delete(ctx, &epicv1.LBServiceGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: epicv1.AccountNamespace(accountName), Name: name},
+		})
+	})
+}
+
+// groupInUse indicates whether a group has any services or proxies.
+// It doesn't retry so callers should.
+func groupInUse(ctx context.Context, cl client.Client, accountName string, name string) (bool, error) {
+	inGroup := client.MatchingLabels{epicv1.OwningLBServiceGroupLabel: name}
+	for _, list := range []client.ObjectList{&epicv1.LoadBalancerList{}, &epicv1.GWProxyList{}} {
+		if err := cl.List(ctx, list, client.InNamespace(epicv1.AccountNamespace(accountName)), inGroup, client.Limit(1)); err != nil {
+			return false, err
+		}
+		if listLen(list) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newGroup builds an LBServiceGroup from a template.
+func newGroup(accountName string, template model.GroupTemplate) epicv1.LBServiceGroup {
+	return epicv1.LBServiceGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: epicv1.AccountNamespace(accountName),
+			Name:      template.Name,
+			Labels: map[string]string{
+				epicv1.OwningAccountLabel:       accountName,
+				epicv1.OwningServicePrefixLabel: template.ServicePrefix,
+			},
+		},
+		Spec: epicv1.LBServiceGroupSpec{CanBeShared: template.CanBeShared},
+	}
+}
@@ -2,40 +2,51 @@ package db
 
 import (
 	"context"
-	"fmt"
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/tracing"
 )
 
 // ReadSlice reads one endpoint slice from the cluster.
-func ReadSlice(ctx context.Context, cl client.Client, accountName string, sliceName string) (*model.Slice, error) {
+func ReadSlice(ctx context.Context, cl client.Client, accountName string, sliceName string) (_ *model.Slice, err error) {
+	ctx, span := startSpan(ctx, "db.ReadSlice", accountName, sliceName)
+	defer func() { tracing.End(span, err) }()
+
 	mslice := model.NewSlice()
-	return &mslice, cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: sliceName}, &mslice.Slice)
+	return &mslice, withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: sliceName}, &mslice.Slice)
+	})
 }
 
 // UpdateSlice updates the provided endpoint slice.
-func UpdateSlice(ctx context.Context, cl client.Client, accountName string, sliceName string, slice *epicv1.GWEndpointSlice) error {
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		model, err := ReadSlice(ctx, cl, accountName, sliceName)
-		if err != nil {
+func UpdateSlice(ctx context.Context, cl client.Client, accountName string, sliceName string, slice *epicv1.GWEndpointSlice) (err error) {
+	ctx, span := startSpan(ctx, "db.UpdateSlice", accountName, sliceName)
+	defer func() { tracing.End(span, err) }()
+
+	return withRetries(ctx, func() error {
+		existing := epicv1.GWEndpointSlice{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: sliceName}, &existing); err != nil {
 			return err
 		}
 
-		slice.Spec.DeepCopyInto(&model.Slice.Spec)
+		slice.Spec.DeepCopyInto(&existing.Spec)
 
-		return cl.Update(ctx, &model.Slice)
+		return cl.Update(ctx, &existing)
 	})
 }
 
 // DeleteSlice deletes the specified endpoint slice.
-func DeleteSlice(ctx context.Context, cl client.Client, accountName string, name string) error {
-	err := cl.Delete(
+func DeleteSlice(ctx context.Context, cl client.Client, accountName string, name string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteSlice", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	err = cl.Delete(
 		ctx,
 		&epicv1.GWEndpointSlice{
 			ObjectMeta: metav1.ObjectMeta{
@@ -48,7 +59,7 @@ func DeleteSlice(ctx context.Context, cl client.Client, accountName string, name
 		if errors.IsNotFound(err) {
 			// Request object not found. Not great, but the client wanted
 			// the object gone and it's gone.
-			fmt.Printf("%s/%s not found. Ignoring since object must be deleted\n", accountName, name)
+			log.FromContext(ctx).Info("not found, ignoring since object must be deleted", "name", name)
 			return nil
 		}
 		return err
@@ -2,52 +2,51 @@ package db
 
 import (
 	"context"
-	"fmt"
-	"time"
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/tracing"
 )
 
 // ReadRoute reads one service route from the cluster.
-func ReadRoute(ctx context.Context, cl client.Client, accountName string, name string) (*model.Route, error) {
-	var err error
+func ReadRoute(ctx context.Context, cl client.Client, accountName string, name string) (_ *model.Route, err error) {
+	ctx, span := startSpan(ctx, "db.ReadRoute", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
 	mroute := model.NewRoute()
-	tries := 2
-	for err = fmt.Errorf(""); err != nil && tries > 0; tries-- {
-		err = cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mroute.Route)
-		if err != nil {
-			fmt.Printf("problem reading route %s/%s: %#v\n", accountName, name, err)
-			if tries > 1 {
-				time.Sleep(1 * time.Second)
-			}
-		}
-	}
-	return &mroute, err
+	return &mroute, withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: name}, &mroute.Route)
+	})
 }
 
 // UpdateRoute updates the provided route.
-func UpdateRoute(ctx context.Context, cl client.Client, accountName string, routeName string, route *epicv1.GWRoute) error {
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		model, err := ReadRoute(ctx, cl, accountName, routeName)
-		if err != nil {
+func UpdateRoute(ctx context.Context, cl client.Client, accountName string, routeName string, route *epicv1.GWRoute) (err error) {
+	ctx, span := startSpan(ctx, "db.UpdateRoute", accountName, routeName)
+	defer func() { tracing.End(span, err) }()
+
+	return withRetries(ctx, func() error {
+		existing := epicv1.GWRoute{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: routeName}, &existing); err != nil {
 			return err
 		}
 
-		route.Spec.DeepCopyInto(&model.Route.Spec)
+		route.Spec.DeepCopyInto(&existing.Spec)
 
-		return cl.Update(ctx, &model.Route)
+		return cl.Update(ctx, &existing)
 	})
 }
 
 // DeleteRoute deletes the specified GWRoute.
-func DeleteRoute(ctx context.Context, cl client.Client, accountName string, name string) error {
-	err := cl.Delete(
+func DeleteRoute(ctx context.Context, cl client.Client, accountName string, name string) (err error) {
+	ctx, span := startSpan(ctx, "db.DeleteRoute", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	err = cl.Delete(
 		ctx,
 		&epicv1.GWRoute{
 			ObjectMeta: metav1.ObjectMeta{
@@ -60,7 +59,7 @@ func DeleteRoute(ctx context.Context, cl client.Client, accountName string, name
 		if errors.IsNotFound(err) {
 			// Request object not found. Not great, but the client wanted
 			// the object gone and it's gone.
-			fmt.Printf("%s/%s not found. Ignoring since object must be deleted\n", accountName, name)
+			log.FromContext(ctx).Info("not found, ignoring since object must be deleted", "name", name)
 			return nil
 		}
 		return err
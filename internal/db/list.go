@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/tracing"
+)
+
+// ListGroups lists the LBServiceGroups in an account.
+func ListGroups(ctx context.Context, cl client.Client, accountName string, opts ...client.ListOption) (_ []model.Group, err error) {
+	ctx, span := startSpan(ctx, "db.ListGroups", accountName, "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.LBServiceGroupList{}
+	if err := listInAccount(ctx, cl, accountName, &list, opts); err != nil {
+		return nil, err
+	}
+
+	groups := make([]model.Group, 0, len(list.Items))
+	for _, item := range list.Items {
+		mgroup := model.NewGroup()
+		mgroup.Group = item
+		groups = append(groups, mgroup)
+	}
+	return groups, nil
+}
+
+// ListServices lists the LoadBalancers in an account.
+func ListServices(ctx context.Context, cl client.Client, accountName string, opts ...client.ListOption) (_ []model.Service, err error) {
+	ctx, span := startSpan(ctx, "db.ListServices", accountName, "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.LoadBalancerList{}
+	if err := listInAccount(ctx, cl, accountName, &list, opts); err != nil {
+		return nil, err
+	}
+
+	services := make([]model.Service, 0, len(list.Items))
+	for _, item := range list.Items {
+		mservice := model.NewService()
+		mservice.Service = item
+		services = append(services, mservice)
+	}
+	return services, nil
+}
+
+// ListRoutes lists the GWRoutes in an account.
+func ListRoutes(ctx context.Context, cl client.Client, accountName string, opts ...client.ListOption) (_ []model.Route, err error) {
+	ctx, span := startSpan(ctx, "db.ListRoutes", accountName, "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.GWRouteList{}
+	if err := listInAccount(ctx, cl, accountName, &list, opts); err != nil {
+		return nil, err
+	}
+
+	routes := make([]model.Route, 0, len(list.Items))
+	for _, item := range list.Items {
+		mroute := model.NewRoute()
+		mroute.Route = item
+		routes = append(routes, mroute)
+	}
+	return routes, nil
+}
+
+// ListSlices lists the GWEndpointSlices in an account.
+func ListSlices(ctx context.Context, cl client.Client, accountName string, opts ...client.ListOption) (_ []model.Slice, err error) {
+	ctx, span := startSpan(ctx, "db.ListSlices", accountName, "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.GWEndpointSliceList{}
+	if err := listInAccount(ctx, cl, accountName, &list, opts); err != nil {
+		return nil, err
+	}
+
+	slices := make([]model.Slice, 0, len(list.Items))
+	for _, item := range list.Items {
+		mslice := model.NewSlice()
+		mslice.Slice = item
+		slices = append(slices, mslice)
+	}
+	return slices, nil
+}
+
+// ListProxies lists the GWProxies in an account.
+func ListProxies(ctx context.Context, cl client.Client, accountName string, opts ...client.ListOption) (_ []model.Proxy, err error) {
+	ctx, span := startSpan(ctx, "db.ListProxies", accountName, "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.GWProxyList{}
+	if err := listInAccount(ctx, cl, accountName, &list, opts); err != nil {
+		return nil, err
+	}
+
+	proxies := make([]model.Proxy, 0, len(list.Items))
+	for _, item := range list.Items {
+		mproxy := model.NewProxy()
+		mproxy.Proxy = item
+		proxies = append(proxies, mproxy)
+	}
+	return proxies, nil
+}
+
+// ListEndpoints lists the RemoteEndpoints in an account.
+func ListEndpoints(ctx context.Context, cl client.Client, accountName string, opts ...client.ListOption) (_ []model.Endpoint, err error) {
+	ctx, span := startSpan(ctx, "db.ListEndpoints", accountName, "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.RemoteEndpointList{}
+	if err := listInAccount(ctx, cl, accountName, &list, opts); err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]model.Endpoint, 0, len(list.Items))
+	for _, item := range list.Items {
+		mendpoint := model.NewEndpoint()
+		mendpoint.Endpoint = item
+		endpoints = append(endpoints, mendpoint)
+	}
+	return endpoints, nil
+}
+
+// listInAccount lists objects in an account's namespace.
+func listInAccount(ctx context.Context, cl client.Client, accountName string, list client.ObjectList, opts []client.ListOption) error {
+	opts = append([]client.ListOption{client.InNamespace(epicv1.AccountNamespace(accountName))}, opts...)
+	return withRetries(ctx, func() error {
+		return cl.List(ctx, list, opts...)
+	})
+}
+
+// listLen returns the number of items in list.
+func listLen(list client.ObjectList) int {
+	return meta.LenList(list)
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"acnodal.io/epic/web-service/internal/tracing"
+)
+
+// FindByAddress lists the LoadBalancers and GWProxies whose public
+// address is ip. If accountName is "" then every account is
+// searched.
+func FindByAddress(ctx context.Context, cl client.Client, accountName string, ip net.IP) (_ []epicv1.LoadBalancer, _ []epicv1.GWProxy, err error) {
+	ctx, span := startSpan(ctx, "db.FindByAddress", accountName, ip.String())
+	defer func() { tracing.End(span, err) }()
+
+	opts := accountScope(accountName)
+	lbs := epicv1.LoadBalancerList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &lbs, opts...) }); err != nil {
+		return nil, nil, err
+	}
+	proxies := epicv1.GWProxyList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &proxies, opts...) }); err != nil {
+		return nil, nil, err
+	}
+
+	foundLBs := []epicv1.LoadBalancer{}
+	for _, lb := range lbs.Items {
+		if ip.Equal(net.ParseIP(lb.Spec.PublicAddress)) {
+			foundLBs = append(foundLBs, lb)
+		}
+	}
+	foundProxies := []epicv1.GWProxy{}
+	for _, proxy := range proxies.Items {
+		if ip.Equal(net.ParseIP(proxy.Spec.PublicAddress)) {
+			foundProxies = append(foundProxies, proxy)
+		}
+	}
+	return foundLBs, foundProxies, nil
+}
+
+// FindByHostname lists the GWRoutes with a hostname that matches
+// hostname, including wildcard hostnames like "*.example.com". If
+// accountName is "" then every account is searched.
+func FindByHostname(ctx context.Context, cl client.Client, accountName string, hostname string) (_ []epicv1.GWRoute, err error) {
+	ctx, span := startSpan(ctx, "db.FindByHostname", accountName, hostname)
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.GWRouteList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &list, accountScope(accountName)...) }); err != nil {
+		return nil, err
+	}
+
+	found := []epicv1.GWRoute{}
+	for _, route := range list.Items {
+		if route.Spec.HTTP == nil {
+			continue
+		}
+		for _, routeHostname := range route.Spec.HTTP.Hostnames {
+			if HostnameMatches(string(routeHostname), hostname) {
+				found = append(found, route)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// HostnameMatches indicates whether hostname matches pattern, which
+// is a hostname or a Gateway API wildcard like "*.example.com" that
+// matches one or more leading labels. Case and trailing dots are
+// ignored.
+func HostnameMatches(pattern string, hostname string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+
+	if strings.HasPrefix(pattern, "*.") {
+		suffix := pattern[1:]
+		return strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix)
+	}
+	return pattern == hostname
+}
+
+// AccountFromNamespace returns the name of the account that owns an
+// account namespace.
+func AccountFromNamespace(namespace string) string {
+	return strings.TrimPrefix(namespace, epicv1.AccountNamespace(""))
+}
+
+// accountScope returns the list options that limit a list to an
+// account, or to nothing if accountName is "".
+func accountScope(accountName string) []client.ListOption {
+	if accountName == "" {
+		return nil
+	}
+	return []client.ListOption{client.InNamespace(epicv1.AccountNamespace(accountName))}
+}
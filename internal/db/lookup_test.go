@@ -0,0 +1,40 @@
+package db
+
+import "testing"
+
+func TestHostnameMatches(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		hostname string
+		want     bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "www.example.com", false},
+		{"www.example.com", "example.com", false},
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", ".example.com", false},
+		{"*.example.com", "wwwexample.com", false},
+		{"*.example.com", "www.example.org", false},
+		{"example.com.", "example.com", true},
+		{"example.com", "example.com.", true},
+		{"*.example.com.", "www.example.com", true},
+		{"*.example.com", "www.example.com.", true},
+		{"Example.COM", "example.com", true},
+		{"*.EXAMPLE.com", "WWW.example.COM", true},
+		{"*.example.com", "WWW.EXAMPLE.COM.", true},
+	}
+
+	for _, test := range tests {
+		if got := HostnameMatches(test.pattern, test.hostname); got != test.want {
+			t.Errorf("HostnameMatches(%q, %q) = %v, want %v", test.pattern, test.hostname, got, test.want)
+		}
+	}
+}
+
+func TestAccountFromNamespace(t *testing.T) {
+	if got := AccountFromNamespace("epic-root"); got != "root" {
+		t.Errorf("AccountFromNamespace(\"epic-root\") = %q, want \"root\"", got)
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"time"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/pool"
+	"acnodal.io/epic/web-service/internal/tracing"
+)
+
+const (
+	// PickConfigMap is the name of the ConfigMap, in each
+	// ServicePrefix's namespace, that records the addresses that we
+	// picked from its pool. Every pick is written with the
+	// ConfigMap's resourceVersion so two requests can't pick the same
+	// address.
+	PickConfigMap = "epic-address-picks"
+
+	// pickHold is how long a pick holds its address. It only needs to
+	// outlast the create and our cache catching up with it.
+	pickHold = 2 * time.Minute
+)
+
+// pick is a value in the PickConfigMap.
+type pick struct {
+	Prefix  string      `json:"prefix"`
+	Account string      `json:"account"`
+	Kind    string      `json:"kind"`
+	Object  string      `json:"object"`
+	Address string      `json:"address"`
+	Expires metav1.Time `json:"expires"`
+}
+
+// pickKey is the PickConfigMap key of an object's pick.
+func pickKey(prefixName string, accountName string, kind string, name string) string {
+	return "pick-" + hashName(prefixName, accountName, kind, name)
+}
+
+// PickAddress picks the first address in a ServicePrefix's pool
+// that's not in used and hasn't been picked for another object, and
+// records it for the account's object of kind and name. It returns
+// nil if there are no free addresses.
+//
+// If another request picks at the same time then one of the writes
+// fails with a conflict and is retried with the other's pick in view.
+func PickAddress(ctx context.Context, cl client.Client, prefix *epicv1.ServicePrefix, addressPool pool.Pool, used map[string]struct{}, accountName string, kind string, name string) (_ net.IP, err error) {
+	ctx, span := startSpan(ctx, "db.PickAddress", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	key := pickKey(prefix.Name, accountName, kind, name)
+	var picked net.IP
+	err = withRetries(ctx, func() error {
+		picked = nil
+
+		cm := corev1.ConfigMap{}
+		exists := true
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: prefix.Namespace, Name: PickConfigMap}, &cm); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			exists = false
+			cm = corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: prefix.Namespace, Name: PickConfigMap}}
+		}
+
+		now := time.Now()
+		picks := activePicks(ctx, &cm, now)
+		taken := map[string]struct{}{}
+		for ip := range used {
+			taken[ip] = struct{}{}
+		}
+		for k, p := range picks {
+			if k != key && p.Prefix == prefix.Name {
+				if ip := net.ParseIP(p.Address); ip != nil {
+					taken[ip.String()] = struct{}{}
+				}
+			}
+		}
+
+		ip := addressPool.FirstFree(taken)
+		if ip == nil {
+			return nil
+		}
+		picks[key] = pick{Prefix: prefix.Name, Account: accountName, Kind: kind, Object: name, Address: ip.String(), Expires: metav1.NewTime(now.Add(pickHold))}
+
+		cm.Data = map[string]string{}
+		for k, p := range picks {
+			bytes, err := json.Marshal(p)
+			if err != nil {
+				return err
+			}
+			cm.Data[k] = string(bytes)
+		}
+		if !exists {
+			err = cl.Create(ctx, &cm)
+		} else {
+			err = cl.Update(ctx, &cm)
+		}
+		if err == nil {
+			picked = ip
+		}
+		return err
+	})
+	return picked, err
+}
+
+// listPicks lists the unexpired picks from prefixes' pools. It reads
+// the PickConfigMap in each of their namespaces once.
+func listPicks(ctx context.Context, cl client.Client, prefixes []epicv1.ServicePrefix) ([]pick, error) {
+	now := time.Now()
+	picks := []pick{}
+	read := map[string]bool{}
+	for _, prefix := range prefixes {
+		if read[prefix.Namespace] {
+			continue
+		}
+		read[prefix.Namespace] = true
+
+		cm := corev1.ConfigMap{}
+		if err := withRetries(ctx, func() error {
+			return cl.Get(ctx, client.ObjectKey{Namespace: prefix.Namespace, Name: PickConfigMap}, &cm)
+		}); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, p := range activePicks(ctx, &cm, now) {
+			picks = append(picks, p)
+		}
+	}
+	return picks, nil
+}
+
+// activePicks decodes the unexpired picks in cm, by key. Ones that
+// can't be decoded are logged and skipped, so they're dropped the
+// next time the ConfigMap is written.
+func activePicks(ctx context.Context, cm *corev1.ConfigMap, now time.Time) map[string]pick {
+	picks := map[string]pick{}
+	for key, value := range cm.Data {
+		p := pick{}
+		if err := json.Unmarshal([]byte(value), &p); err != nil {
+			log.FromContext(ctx).Error(err, "invalid address pick, ignoring", "namespace", cm.Namespace, "key", key)
+			continue
+		}
+		if !p.Expires.Time.After(now) {
+			continue
+		}
+		picks[key] = p
+	}
+	return picks
+}
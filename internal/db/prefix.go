@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"acnodal.io/epic/web-service/internal/pool"
+	"acnodal.io/epic/web-service/internal/tracing"
+)
+
+// ListPrefixes lists every ServicePrefix.
+func ListPrefixes(ctx context.Context, cl client.Client) (_ []epicv1.ServicePrefix, err error) {
+	ctx, span := startSpan(ctx, "db.ListPrefixes", "", "")
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.ServicePrefixList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &list) }); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// ReadPrefix reads one ServicePrefix. Groups refer to prefixes by
+// name alone so we look in every namespace.
+func ReadPrefix(ctx context.Context, cl client.Client, name string) (_ *epicv1.ServicePrefix, err error) {
+	ctx, span := startSpan(ctx, "db.ReadPrefix", "", name)
+	defer func() { tracing.End(span, err) }()
+
+	prefixes, err := ListPrefixes(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+	for i := range prefixes {
+		if prefixes[i].Name == name {
+			return &prefixes[i], nil
+		}
+	}
+	return nil, errors.NewNotFound(epicv1.GroupVersion.WithResource("serviceprefixes").GroupResource(), name)
+}
+
+// ListPrefixGroups lists the LBServiceGroups in every account that
+// allocate from a ServicePrefix.
+func ListPrefixGroups(ctx context.Context, cl client.Client, prefixName string) (_ []epicv1.LBServiceGroup, err error) {
+	ctx, span := startSpan(ctx, "db.ListPrefixGroups", "", prefixName)
+	defer func() { tracing.End(span, err) }()
+
+	list := epicv1.LBServiceGroupList{}
+	if err := withRetries(ctx, func() error {
+		return cl.List(ctx, &list, client.MatchingLabels{epicv1.OwningServicePrefixLabel: prefixName})
+	}); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// ReservationKind is the AddressHolder kind of reservations.
+const ReservationKind = "Reservation"
+
+// AddressHolder is an object that holds a public address. Picked
+// holders are objects that we picked an address for but that might
+// not exist yet.
+type AddressHolder struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Address   string
+	Picked    bool
+}
+
+// ListPrefixHolders lists the LoadBalancers, GWProxies, reservations
+// and picks in every account that hold addresses from a
+// ServicePrefix. Shared services can have the same address so an
+// address can have more than one holder.
+func ListPrefixHolders(ctx context.Context, cl client.Client, prefix *epicv1.ServicePrefix) (_ []AddressHolder, err error) {
+	ctx, span := startSpan(ctx, "db.ListPrefixHolders", "", prefix.Name)
+	defer func() { tracing.End(span, err) }()
+
+	holders, err := ListHolders(ctx, cl, []epicv1.ServicePrefix{*prefix})
+	if err != nil {
+		return nil, err
+	}
+	return holders[prefix.Name], nil
+}
+
+// ListHolders lists the address holders of several ServicePrefixes,
+// by prefix name. It reads each account's reservations and each
+// prefix namespace's picks once however many prefixes there are.
+func ListHolders(ctx context.Context, cl client.Client, prefixes []epicv1.ServicePrefix) (_ map[string][]AddressHolder, err error) {
+	ctx, span := startSpan(ctx, "db.ListHolders", "", "")
+	defer func() { tracing.End(span, err) }()
+
+	holders := map[string][]AddressHolder{}
+	for _, prefix := range prefixes {
+		holders[prefix.Name] = []AddressHolder{}
+	}
+	add := func(prefixName string, holder AddressHolder) {
+		if _, ok := holders[prefixName]; ok && holder.Address != "" {
+			holders[prefixName] = append(holders[prefixName], holder)
+		}
+	}
+
+	lbs := epicv1.LoadBalancerList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &lbs) }); err != nil {
+		return nil, err
+	}
+	for _, lb := range lbs.Items {
+		add(lb.Labels[epicv1.OwningServicePrefixLabel], AddressHolder{Kind: "LoadBalancer", Namespace: lb.Namespace, Name: lb.Name, Address: lb.Spec.PublicAddress})
+	}
+	proxies := epicv1.GWProxyList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &proxies) }); err != nil {
+		return nil, err
+	}
+	for _, proxy := range proxies.Items {
+		add(proxy.Labels[epicv1.OwningServicePrefixLabel], AddressHolder{Kind: "GWProxy", Namespace: proxy.Namespace, Name: proxy.Name, Address: proxy.Spec.PublicAddress})
+	}
+
+	reservations, err := listAllReservations(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+	for _, reservation := range reservations {
+		add(reservation.Prefix, AddressHolder{Kind: ReservationKind, Namespace: epicv1.AccountNamespace(reservation.Account), Name: reservation.Name, Address: reservation.Address})
+	}
+
+	picks, err := listPicks(ctx, cl, prefixes)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range picks {
+		add(p.Prefix, AddressHolder{Kind: p.Kind, Namespace: epicv1.AccountNamespace(p.Account), Name: p.Object, Address: p.Address, Picked: true})
+	}
+
+	return holders, nil
+}
+
+// PrefixUsage measures how much of a ServicePrefix's pool is
+// allocated. addressPool is the prefix's parsed pool.
+func PrefixUsage(ctx context.Context, cl client.Client, prefix *epicv1.ServicePrefix, addressPool pool.Pool) (_ pool.Usage, err error) {
+	ctx, span := startSpan(ctx, "db.PrefixUsage", "", prefix.Name)
+	defer func() { tracing.End(span, err) }()
+
+	holders, err := ListPrefixHolders(ctx, cl, prefix)
+	if err != nil {
+		return pool.Usage{}, err
+	}
+	return HoldersUsage(addressPool, holders), nil
+}
+
+// HoldersUsage measures how much of a pool holders are using.
+func HoldersUsage(addressPool pool.Pool, holders []AddressHolder) pool.Usage {
+	addresses := make([]string, 0, len(holders))
+	for _, holder := range holders {
+		addresses = append(addresses, holder.Address)
+	}
+	return addressPool.Usage(addresses)
+}
@@ -0,0 +1,286 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/tracing"
+)
+
+const (
+	// ReservationConfigMap is the name of the ConfigMap in each
+	// account's namespace that holds its address reservations. Each
+	// key is a reservation name and each value is a JSON
+	// model.Reservation. The manager doesn't cache ConfigMaps so
+	// reads get the latest version from the API server.
+	ReservationConfigMap = "epic-address-reservations"
+
+	// ReservationLabel marks reservation ConfigMaps.
+	ReservationLabel = "epic.acnodal.io/address-reservations"
+)
+
+// ReservationOptions control how long addresses are held.
+type ReservationOptions struct {
+	// TTL is how long an address is held after its LoadBalancer or
+	// GWProxy is deleted. Zero disables holds.
+	TTL time.Duration
+
+	// MaxTTL is the longest that a client can extend a reservation
+	// for, counting from now.
+	MaxTTL time.Duration
+}
+
+// DefaultReservationOptions returns the default reservation options.
+func DefaultReservationOptions() ReservationOptions {
+	return ReservationOptions{
+		TTL:    24 * time.Hour,
+		MaxTTL: 7 * 24 * time.Hour,
+	}
+}
+
+// Validate checks that o makes sense.
+func (o ReservationOptions) Validate() error {
+	if o.TTL < 0 {
+		return fmt.Errorf("reservation TTL must not be negative")
+	}
+	if o.MaxTTL < o.TTL {
+		return fmt.Errorf("reservation max TTL must be at least the TTL")
+	}
+	return nil
+}
+
+var (
+	reservationMu      sync.RWMutex
+	reservationOptions = DefaultReservationOptions()
+)
+
+// SetReservationOptions sets the reservation options. It should be
+// called once at startup before any requests are handled.
+func SetReservationOptions(o ReservationOptions) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	reservationMu.Lock()
+	defer reservationMu.Unlock()
+	reservationOptions = o
+	return nil
+}
+
+// CurrentReservationOptions returns the reservation options.
+func CurrentReservationOptions() ReservationOptions {
+	reservationMu.RLock()
+	defer reservationMu.RUnlock()
+	return reservationOptions
+}
+
+// ProxyReservationName is the name of the reservation that a deleted
+// GWProxy leaves. It's based on the proxy's ClientRef, not its UID,
+// so a Gateway that's re-created with the same cluster, namespace and
+// name gets it.
+func ProxyReservationName(ref epicv1.ClientRef) string {
+	return "gwproxy-" + hashName(ref.ClusterID, ref.Namespace, ref.Name)
+}
+
+// ServiceReservationName is the name of the reservation that a
+// deleted LoadBalancer leaves.
+func ServiceReservationName(lbName string) string {
+	return "loadbalancer-" + hashName(lbName)
+}
+
+// hashName returns a short hash of parts that's a valid ConfigMap key.
+func hashName(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:20]
+}
+
+// ListReservations lists an account's unexpired reservations, sorted
+// by name.
+func ListReservations(ctx context.Context, cl client.Client, accountName string) (_ []model.Reservation, err error) {
+	ctx, span := startSpan(ctx, "db.ListReservations", accountName, "")
+	defer func() { tracing.End(span, err) }()
+
+	cm := corev1.ConfigMap{}
+	if err := withRetries(ctx, func() error {
+		return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: ReservationConfigMap}, &cm)
+	}); err != nil {
+		if errors.IsNotFound(err) {
+			return []model.Reservation{}, nil
+		}
+		return nil, err
+	}
+	return activeReservations(ctx, &cm, time.Now()), nil
+}
+
+// ReadReservation reads one of an account's reservations. It returns
+// a NotFound error if there's no such reservation or it has expired.
+func ReadReservation(ctx context.Context, cl client.Client, accountName string, name string) (_ *model.Reservation, err error) {
+	ctx, span := startSpan(ctx, "db.ReadReservation", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	reservations, err := ListReservations(ctx, cl, accountName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reservations {
+		if reservations[i].Name == name {
+			return &reservations[i], nil
+		}
+	}
+	return nil, reservationNotFound(name)
+}
+
+// listAllReservations lists the unexpired reservations in every
+// account. We're only allowed to read the reservation ConfigMaps, not
+// list ConfigMaps, so it reads each account's.
+func listAllReservations(ctx context.Context, cl client.Client) ([]model.Reservation, error) {
+	accounts := epicv1.AccountList{}
+	if err := withRetries(ctx, func() error { return cl.List(ctx, &accounts) }); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reservations := []model.Reservation{}
+	for _, account := range accounts.Items {
+		cm := corev1.ConfigMap{}
+		if err := withRetries(ctx, func() error {
+			return cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(account.Name), Name: ReservationConfigMap}, &cm)
+		}); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		reservations = append(reservations, activeReservations(ctx, &cm, now)...)
+	}
+	return reservations, nil
+}
+
+// Reserve adds a reservation to an account, replacing any with the
+// same name.
+func Reserve(ctx context.Context, cl client.Client, accountName string, reservation model.Reservation) (err error) {
+	ctx, span := startSpan(ctx, "db.Reserve", accountName, reservation.Name)
+	defer func() { tracing.End(span, err) }()
+
+	return updateReservations(ctx, cl, accountName, func(reservations map[string]model.Reservation) error {
+		reservations[reservation.Name] = reservation
+		return nil
+	})
+}
+
+// ExtendReservation changes when a reservation expires.
+func ExtendReservation(ctx context.Context, cl client.Client, accountName string, name string, expires time.Time) (err error) {
+	ctx, span := startSpan(ctx, "db.ExtendReservation", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	return updateReservations(ctx, cl, accountName, func(reservations map[string]model.Reservation) error {
+		reservation, exists := reservations[name]
+		if !exists {
+			return reservationNotFound(name)
+		}
+		reservation.Expires = metav1.NewTime(expires)
+		reservations[name] = reservation
+		return nil
+	})
+}
+
+// ReleaseReservation deletes a reservation so its address can be
+// allocated again. It's not an error if the reservation doesn't
+// exist.
+func ReleaseReservation(ctx context.Context, cl client.Client, accountName string, name string) (err error) {
+	ctx, span := startSpan(ctx, "db.ReleaseReservation", accountName, name)
+	defer func() { tracing.End(span, err) }()
+
+	return updateReservations(ctx, cl, accountName, func(reservations map[string]model.Reservation) error {
+		delete(reservations, name)
+		return nil
+	})
+}
+
+// updateReservations applies update to an account's unexpired
+// reservations and writes them back, creating the ConfigMap if
+// necessary. Expired reservations are dropped.
+func updateReservations(ctx context.Context, cl client.Client, accountName string, update func(map[string]model.Reservation) error) error {
+	return withRetries(ctx, func() error {
+		cm := corev1.ConfigMap{}
+		key := client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: ReservationConfigMap}
+		exists := true
+		if err := cl.Get(ctx, key, &cm); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			exists = false
+			cm = corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+				Labels:    map[string]string{ReservationLabel: "true", epicv1.OwningAccountLabel: accountName},
+			}}
+		}
+
+		reservations := map[string]model.Reservation{}
+		for _, reservation := range activeReservations(ctx, &cm, time.Now()) {
+			reservations[reservation.Name] = reservation
+		}
+		if err := update(reservations); err != nil {
+			return err
+		}
+
+		cm.Data = map[string]string{}
+		for name, reservation := range reservations {
+			reservation.Links = nil
+			bytes, err := json.Marshal(reservation)
+			if err != nil {
+				return err
+			}
+			cm.Data[name] = string(bytes)
+		}
+
+		if !exists {
+			return cl.Create(ctx, &cm)
+		}
+		return cl.Update(ctx, &cm)
+	})
+}
+
+// activeReservations decodes the unexpired reservations in cm. Ones
+// that can't be decoded are logged and skipped.
+func activeReservations(ctx context.Context, cm *corev1.ConfigMap, now time.Time) []model.Reservation {
+	reservations := []model.Reservation{}
+	for name, value := range cm.Data {
+		reservation := model.Reservation{}
+		if err := json.Unmarshal([]byte(value), &reservation); err != nil {
+			log.FromContext(ctx).Error(err, "invalid reservation, ignoring", "namespace", cm.Namespace, "name", name)
+			continue
+		}
+		if !reservation.Expires.Time.After(now) {
+			continue
+		}
+		reservation.Name = name
+		reservation.Account = cm.Labels[epicv1.OwningAccountLabel]
+		reservation.Links = model.Links{}
+		reservations = append(reservations, reservation)
+	}
+	sort.Slice(reservations, func(i, j int) bool { return reservations[i].Name < reservations[j].Name })
+	return reservations
+}
+
+func reservationNotFound(name string) error {
+	return errors.NewNotFound(corev1.Resource("reservation"), name)
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Error classes that can be retried.
+const (
+	// RetryConflict is an optimistic concurrency failure, i.e., someone
+	// else updated the object between our read and our write.
+	RetryConflict = "conflict"
+
+	// RetryThrottling means that the API server asked us to back off.
+	RetryThrottling = "throttling"
+
+	// RetryTimeout means that the API server or the connection to it
+	// timed out.
+	RetryTimeout = "timeout"
+)
+
+// Options configures the db layer's retry policy. Retries back off
+// exponentially from InitialInterval, multiplying by Multiplier each
+// time up to MaxInterval. Each interval is randomized by +/- Jitter
+// (a fraction of the interval). We stop retrying after MaxAttempts
+// attempts or MaxElapsed time, whichever comes first, or if the
+// request's context is canceled. A zero MaxElapsed doesn't limit the
+// time.
+type Options struct {
+	InitialInterval time.Duration
+	Multiplier      float64
+	MaxInterval     time.Duration
+	Jitter          float64
+	MaxElapsed      time.Duration
+	MaxAttempts     int
+
+	// RetryOn lists the classes of error that are retried. Valid
+	// values are RetryConflict, RetryThrottling and RetryTimeout.
+	RetryOn []string
+}
+
+// DefaultOptions returns the default retry policy.
+func DefaultOptions() Options {
+	return Options{
+		InitialInterval: 10 * time.Millisecond,
+		Multiplier:      2.0,
+		MaxInterval:     1 * time.Second,
+		Jitter:          0.1,
+		MaxElapsed:      10 * time.Second,
+		MaxAttempts:     5,
+		RetryOn:         []string{RetryConflict, RetryThrottling, RetryTimeout},
+	}
+}
+
+// Validate checks that the options make sense.
+func (o Options) Validate() error {
+	if o.InitialInterval <= 0 {
+		return fmt.Errorf("retry initial interval must be positive")
+	}
+	if o.Multiplier < 1.0 {
+		return fmt.Errorf("retry multiplier must be at least 1.0")
+	}
+	if o.MaxInterval < o.InitialInterval {
+		return fmt.Errorf("retry max interval must be at least the initial interval")
+	}
+	if o.Jitter < 0.0 || o.Jitter > 1.0 {
+		return fmt.Errorf("retry jitter must be between 0.0 and 1.0")
+	}
+	if o.MaxElapsed < 0 {
+		return fmt.Errorf("retry max elapsed time can't be negative")
+	}
+	if o.MaxAttempts < 1 {
+		return fmt.Errorf("retry max attempts must be at least 1")
+	}
+	for _, class := range o.RetryOn {
+		switch class {
+		case RetryConflict, RetryThrottling, RetryTimeout:
+		default:
+			return fmt.Errorf("invalid retry error class %q", class)
+		}
+	}
+	return nil
+}
+
+var (
+	optionsMu sync.RWMutex
+	options   = DefaultOptions()
+)
+
+// SetOptions sets the db layer's retry policy. It should be called
+// once at startup before any requests are handled.
+func SetOptions(o Options) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	options = o
+	return nil
+}
+
+func currentOptions() Options {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+	return options
+}
+
+// classify returns the retry class of err, or "" if err isn't
+// retryable.
+func classify(err error) string {
+	switch {
+	case errors.IsConflict(err):
+		return RetryConflict
+	case errors.IsTooManyRequests(err):
+		return RetryThrottling
+	case errors.IsServerTimeout(err), errors.IsTimeout(err):
+		return RetryTimeout
+	}
+	return ""
+}
+
+// retryable returns true if o says that err should be retried.
+func (o Options) retryable(err error) bool {
+	class := classify(err)
+	if class == "" {
+		return false
+	}
+	for _, allowed := range o.RetryOn {
+		if allowed == class {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetries calls fn until it succeeds, it returns an error that
+// isn't retryable, the retry policy is exhausted, or ctx is canceled.
+// It returns fn's last error.
+func withRetries(ctx context.Context, fn func() error) error {
+	o := currentOptions()
+	start := time.Now()
+	interval := o.InitialInterval
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !o.retryable(err) || attempt >= o.MaxAttempts {
+			return err
+		}
+
+		delay := jitter(interval, o.Jitter)
+		// If the API server told us how long to wait then respect that.
+		if seconds, ok := errors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+		}
+		if o.MaxElapsed > 0 && time.Since(start)+delay > o.MaxElapsed {
+			return err
+		}
+
+		log.FromContext(ctx).V(1).Info("retrying", "attempt", attempt, "delay", delay, "error", err.Error())
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("class", classify(err)),
+			attribute.String("delay", delay.String()),
+		))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * o.Multiplier)
+		if interval > o.MaxInterval {
+			interval = o.MaxInterval
+		}
+	}
+}
+
+// jitter randomizes d by +/- factor.
+func jitter(d time.Duration, factor float64) time.Duration {
+	if factor == 0 {
+		return d
+	}
+	delta := factor * float64(d)
+	return time.Duration(float64(d) - delta + rand.Float64()*2*delta)
+}
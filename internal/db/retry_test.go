@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Options)
+		valid  bool
+	}{
+		{"defaults", func(o *Options) {}, true},
+		{"no elapsed limit", func(o *Options) { o.MaxElapsed = 0 }, true},
+		{"negative max elapsed", func(o *Options) { o.MaxElapsed = -time.Second }, false},
+		{"zero initial interval", func(o *Options) { o.InitialInterval = 0 }, false},
+		{"max interval below initial", func(o *Options) { o.MaxInterval = o.InitialInterval / 2 }, false},
+		{"zero max attempts", func(o *Options) { o.MaxAttempts = 0 }, false},
+		{"unknown error class", func(o *Options) { o.RetryOn = []string{"everything"} }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := DefaultOptions()
+			test.modify(&o)
+			if err := o.Validate(); (err == nil) != test.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
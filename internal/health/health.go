@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const (
+	// checkTimeout limits how long any one check can take.
+	checkTimeout = 5 * time.Second
+)
+
+var (
+	// RequiredResources are the EPIC custom resources that the web
+	// service can't work without.
+	RequiredResources = []string{"gwproxies", "gwroutes", "gwendpointslices", "loadbalancers"}
+)
+
+// NamedCheck is a health check and the name under which its result
+// is reported.
+type NamedCheck struct {
+	Name  string
+	Check healthz.Checker
+}
+
+// Checks is an ordered set of health checks.
+type Checks []NamedCheck
+
+// Add appends a check to the set.
+func (c *Checks) Add(name string, check healthz.Checker) {
+	*c = append(*c, NamedCheck{Name: name, Check: check})
+}
+
+// CacheSynced checks that the informer cache has synced.
+func CacheSynced(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), checkTimeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return fmt.Errorf("informer cache not synced")
+		}
+		return nil
+	}
+}
+
+// APIServerReachable checks that we can talk to the Kubernetes API
+// server.
+func APIServerReachable(dc discovery.DiscoveryInterface) healthz.Checker {
+	return func(req *http.Request) error {
+		if _, err := dc.ServerVersion(); err != nil {
+			return fmt.Errorf("API server unreachable: %w", err)
+		}
+		return nil
+	}
+}
+
+// CRDsInstalled checks that the API server serves each of the
+// RequiredResources.
+func CRDsInstalled(dc discovery.DiscoveryInterface) healthz.Checker {
+	groupVersion := epicv1.GroupVersion.String()
+
+	return func(req *http.Request) error {
+		resources, err := dc.ServerResourcesForGroupVersion(groupVersion)
+		if err != nil {
+			return fmt.Errorf("reading %s resources: %w", groupVersion, err)
+		}
+
+		served := map[string]bool{}
+		for _, resource := range resources.APIResources {
+			served[resource.Name] = true
+		}
+
+		missing := []string{}
+		for _, name := range RequiredResources {
+			if !served[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%s CRDs not installed: %v", groupVersion, missing)
+		}
+
+		return nil
+	}
+}
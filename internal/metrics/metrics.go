@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	epicv1 "epic-gateway.org/resource-model/api/v1"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"acnodal.io/epic/web-service/internal/util"
+)
+
+const (
+	namespace = "epic"
+	subsystem = "web_service"
+
+	// otherAccount is the account label of requests whose URL names
+	// an account that doesn't exist, so clients can't add label values
+	// by making up account names.
+	otherAccount = "other"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests handled, by route, method, status code and account.",
+		},
+		[]string{"route", "method", "code", "account"},
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency, by route, method, status code and account.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "code", "account"},
+	)
+
+	requestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being handled, by route, method and account.",
+		},
+		[]string{"route", "method", "account"},
+	)
+)
+
+func init() {
+	// Register with controller-runtime's registry so our metrics are
+	// served by the manager's existing metrics endpoint.
+	ctrlmetrics.Registry.MustRegister(requestsTotal, requestDuration, requestsInFlight)
+}
+
+// NewMiddleware returns middleware that records request count,
+// latency and in-flight requests for each request that the router
+// dispatches. Requests are labeled with their account only if it
+// exists, which is checked with cl, so cl should read from the cache.
+func NewMiddleware(cl client.Reader) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := util.RouteName(r)
+			account := accountLabel(r.Context(), cl, mux.Vars(r)["account"])
+
+			inFlight := requestsInFlight.WithLabelValues(route, r.Method, account)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			rec := util.NewStatusRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			code := strconv.Itoa(rec.Status)
+			requestsTotal.WithLabelValues(route, r.Method, code, account).Inc()
+			requestDuration.WithLabelValues(route, r.Method, code, account).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// accountLabel is the account label of a request for accountName:
+// the name itself if the Account exists, "" if the route has no
+// account, and otherAccount otherwise.
+func accountLabel(ctx context.Context, cl client.Reader, accountName string) string {
+	if accountName == "" {
+		return ""
+	}
+	account := epicv1.Account{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: epicv1.AccountNamespace(accountName), Name: accountName}, &account); err != nil {
+		return otherAccount
+	}
+	return accountName
+}
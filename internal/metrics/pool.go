@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/pool"
+)
+
+const (
+	// poolCollectTimeout limits how long a scrape waits for the pool
+	// usage.
+	poolCollectTimeout = 10 * time.Second
+
+	// poolCollectInterval is how often the pool usage is measured.
+	// Scrapes in between get the last measurement so scraping more
+	// often doesn't load the API server more.
+	poolCollectInterval = 30 * time.Second
+)
+
+var (
+	poolSize = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "prefix_pool_size"),
+		"Number of addresses in each service prefix's pool.",
+		[]string{"prefix"}, nil,
+	)
+	poolAllocated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "prefix_pool_allocated"),
+		"Number of addresses in each service prefix's pool that LoadBalancers, GWProxies, reservations or recent picks are holding.",
+		[]string{"prefix"}, nil,
+	)
+	poolFree = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "prefix_pool_free"),
+		"Number of free addresses in each service prefix's pool.",
+		[]string{"prefix"}, nil,
+	)
+)
+
+// PoolCollector exports the size, allocated and free addresses of
+// each ServicePrefix's pool. They're computed from the existing
+// LoadBalancers and GWProxies, which cl should read from the cache,
+// and the reservations and picks, which are read from the API server.
+// They're measured at most once per poolCollectInterval, however often
+// Prometheus scrapes.
+type PoolCollector struct {
+	client client.Client
+
+	mu       sync.Mutex
+	measured time.Time
+	metrics  []prometheus.Metric
+}
+
+// NewPoolCollector configures a new PoolCollector.
+func NewPoolCollector(cl client.Client) *PoolCollector {
+	return &PoolCollector{client: cl}
+}
+
+// RegisterPoolCollector registers a PoolCollector with
+// controller-runtime's registry.
+func RegisterPoolCollector(cl client.Client) error {
+	return ctrlmetrics.Registry.Register(NewPoolCollector(cl))
+}
+
+// Describe implements prometheus.Collector.
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolSize
+	ch <- poolAllocated
+	ch <- poolFree
+}
+
+// Collect implements prometheus.Collector.
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.measured) >= poolCollectInterval {
+		c.metrics = c.measure()
+		c.measured = time.Now()
+	}
+	for _, metric := range c.metrics {
+		ch <- metric
+	}
+}
+
+// measure measures every prefix's pool usage. It reads the holders of
+// all of the prefixes at once. Prefixes whose pools can't be parsed
+// are skipped, and if the holders can't be read there are no metrics.
+func (c *PoolCollector) measure() []prometheus.Metric {
+	log := ctrl.Log.WithName("metrics")
+	ctx, cancel := context.WithTimeout(context.Background(), poolCollectTimeout)
+	defer cancel()
+
+	prefixes, err := db.ListPrefixes(ctx, c.client)
+	if err != nil {
+		log.Error(err, "listing service prefixes failed")
+		return nil
+	}
+	holders, err := db.ListHolders(ctx, c.client, prefixes)
+	if err != nil {
+		log.Error(err, "listing address holders failed")
+		return nil
+	}
+
+	metrics := []prometheus.Metric{}
+	for _, prefix := range prefixes {
+		addressPool, err := pool.Parse(prefix.Spec.Pool)
+		if err != nil {
+			log.Error(err, "invalid service prefix pool", "prefix", prefix.Name)
+			continue
+		}
+		usage := db.HoldersUsage(addressPool, holders[prefix.Name])
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(poolSize, prometheus.GaugeValue, toFloat(usage.Total), prefix.Name),
+			prometheus.MustNewConstMetric(poolAllocated, prometheus.GaugeValue, toFloat(usage.Allocated), prefix.Name),
+			prometheus.MustNewConstMetric(poolFree, prometheus.GaugeValue, toFloat(usage.Free), prefix.Name),
+		)
+	}
+	return metrics
+}
+
+func toFloat(i *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SelectFields returns a copy of obj with only the fields in paths,
+// for clients that ask for a sparse response with the "fields" query
+// parameter. Paths are dot-separated JSON field names, e.g.,
+// "proxy.spec.public-address" or "link.self". A path that names an
+// object keeps all of it, and a path that goes through an array
+// applies to each of its elements. Paths that don't match anything
+// are ignored, since not every object has every optional field.
+func SelectFields(obj interface{}, paths []string) (interface{}, error) {
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(bytes, &doc); err != nil {
+		return nil, err
+	}
+
+	tree := fieldTree{}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		node := tree
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" {
+				return nil, fmt.Errorf("invalid field path %q", path)
+			}
+			next, ok := node[segment]
+			if !ok {
+				next = fieldTree{}
+				node[segment] = next
+			}
+			node = next
+		}
+		node[selectAll] = nil
+	}
+
+	return tree.apply(doc), nil
+}
+
+// fieldTree is a set of field paths. A tree with the selectAll key
+// selects everything below it.
+type fieldTree map[string]fieldTree
+
+// selectAll marks the end of a path. It can't collide with a field
+// name because empty path segments are invalid.
+const selectAll = ""
+
+func (t fieldTree) apply(doc interface{}) interface{} {
+	if _, all := t[selectAll]; all || len(t) == 0 {
+		return doc
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, subtree := range t {
+			if value, ok := v[key]; ok {
+				out[key] = subtree.apply(value)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			out = append(out, t.apply(item))
+		}
+		return out
+	default:
+		// A path goes below a scalar so it can't match anything.
+		return doc
+	}
+}
@@ -1,7 +1,10 @@
 package model
 
 import (
+	"encoding/json"
+
 	epicv1 "epic-gateway.org/resource-model/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Links is a map of URL links from this object to others. Keys are
@@ -9,6 +12,17 @@ import (
 // strings.
 type Links map[string]string
 
+// Embedded holds related objects that the client asked to have
+// inlined with the "embed" query parameter, so it doesn't have to
+// fetch them one by one.
+type Embedded struct {
+	Group     *Group     `json:"group,omitempty"`
+	Proxies   []Proxy    `json:"proxies,omitempty"`
+	Routes    []Route    `json:"routes,omitempty"`
+	Slices    []Slice    `json:"slices,omitempty"`
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+}
+
 // Account represents an account on the wire.
 type Account struct {
 	Links   Links          `json:"link"`
@@ -39,8 +53,9 @@ func NewGroup() Group {
 
 // Service represents a load balancer service on the wire.
 type Service struct {
-	Links   Links               `json:"link"`
-	Service epicv1.LoadBalancer `json:"service"`
+	Links    Links               `json:"link"`
+	Service  epicv1.LoadBalancer `json:"service"`
+	Embedded *Embedded           `json:"embedded,omitempty"`
 }
 
 // NewService configures a new Service instance.
@@ -53,8 +68,9 @@ func NewService() Service {
 
 // Proxy represents a load balancer service on the wire.
 type Proxy struct {
-	Links Links          `json:"link"`
-	Proxy epicv1.GWProxy `json:"proxy"`
+	Links    Links          `json:"link"`
+	Proxy    epicv1.GWProxy `json:"proxy"`
+	Embedded *Embedded      `json:"embedded,omitempty"`
 }
 
 // NewProxy configures a new Proxy instance.
@@ -67,8 +83,9 @@ func NewProxy() Proxy {
 
 // Slice represents an EndpointSlice on the wire.
 type Slice struct {
-	Links Links                  `json:"link"`
-	Slice epicv1.GWEndpointSlice `json:"slice"`
+	Links    Links                  `json:"link"`
+	Slice    epicv1.GWEndpointSlice `json:"slice"`
+	Embedded *Embedded              `json:"embedded,omitempty"`
 }
 
 // NewSlice configures a new Slice instance.
@@ -107,8 +124,9 @@ func NewEndpoint() Endpoint {
 
 // Route represents a GWRoute on the wire.
 type Route struct {
-	Links Links          `json:"link"`
-	Route epicv1.GWRoute `json:"route"`
+	Links    Links          `json:"link"`
+	Route    epicv1.GWRoute `json:"route"`
+	Embedded *Embedded      `json:"embedded,omitempty"`
 }
 
 // NewRoute configures a new Route instance.
@@ -118,3 +136,238 @@ func NewRoute() Route {
 		Route: epicv1.GWRoute{},
 	}
 }
+
+// GroupList represents a list of Service Groups on the wire.
+type GroupList struct {
+	Links  Links   `json:"link"`
+	Groups []Group `json:"groups"`
+}
+
+// NewGroupList configures a new GroupList instance.
+func NewGroupList() GroupList {
+	return GroupList{
+		Links:  Links{},
+		Groups: []Group{},
+	}
+}
+
+// ServiceList represents a list of load balancer services on the
+// wire.
+type ServiceList struct {
+	Links    Links     `json:"link"`
+	Services []Service `json:"services"`
+}
+
+// NewServiceList configures a new ServiceList instance.
+func NewServiceList() ServiceList {
+	return ServiceList{
+		Links:    Links{},
+		Services: []Service{},
+	}
+}
+
+// ProxyList represents a list of GWProxies on the wire.
+type ProxyList struct {
+	Links   Links   `json:"link"`
+	Proxies []Proxy `json:"proxies"`
+}
+
+// NewProxyList configures a new ProxyList instance.
+func NewProxyList() ProxyList {
+	return ProxyList{
+		Links:   Links{},
+		Proxies: []Proxy{},
+	}
+}
+
+// Summary is an inventory of an account's objects, with anything
+// that looks wrong flagged as an Anomaly.
+type Summary struct {
+	Links     Links          `json:"link"`
+	Account   string         `json:"account"`
+	Counts    map[string]int `json:"counts"`
+	Groups    []SummaryItem  `json:"groups"`
+	Services  []SummaryItem  `json:"services"`
+	Proxies   []SummaryItem  `json:"proxies"`
+	Routes    []SummaryItem  `json:"routes"`
+	Slices    []SummaryItem  `json:"slices"`
+	Endpoints []SummaryItem  `json:"endpoints"`
+	Anomalies []Anomaly      `json:"anomalies"`
+}
+
+// NewSummary configures a new Summary instance.
+func NewSummary(account string) Summary {
+	return Summary{
+		Links:     Links{},
+		Account:   account,
+		Counts:    map[string]int{},
+		Groups:    []SummaryItem{},
+		Services:  []SummaryItem{},
+		Proxies:   []SummaryItem{},
+		Routes:    []SummaryItem{},
+		Slices:    []SummaryItem{},
+		Endpoints: []SummaryItem{},
+		Anomalies: []Anomaly{},
+	}
+}
+
+// SummaryItem is a brief description of one object in a Summary.
+type SummaryItem struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Group       string   `json:"group,omitempty"`
+	Address     string   `json:"address,omitempty"`
+	Cluster     string   `json:"cluster,omitempty"`
+	Hostnames   []string `json:"hostnames,omitempty"`
+	Link        string   `json:"link,omitempty"`
+}
+
+// Anomaly is something in an account that's probably wrong.
+type Anomaly struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// AccountList represents a list of accounts on the wire.
+type AccountList struct {
+	Links    Links     `json:"link"`
+	Accounts []Account `json:"accounts"`
+}
+
+// NewAccountList configures a new AccountList instance.
+func NewAccountList() AccountList {
+	return AccountList{
+		Links:    Links{},
+		Accounts: []Account{},
+	}
+}
+
+// GroupTemplate describes an LBServiceGroup that's created along
+// with a new account.
+type GroupTemplate struct {
+	Name          string `json:"name"`
+	ServicePrefix string `json:"servicePrefix"`
+	CanBeShared   bool   `json:"canBeShared,omitempty"`
+}
+
+// AccountDeletion reports what an account deletion removes.
+type AccountDeletion struct {
+	Account string         `json:"account"`
+	DryRun  bool           `json:"dryRun,omitempty"`
+	Deleted map[string]int `json:"deleted"`
+}
+
+// Prefix represents a ServicePrefix on the wire, with its pool's
+// usage and the groups that allocate from it.
+type Prefix struct {
+	Links  Links                `json:"link"`
+	Prefix epicv1.ServicePrefix `json:"prefix"`
+	Pool   PoolUsage            `json:"pool"`
+	Groups []GroupRef           `json:"groups"`
+}
+
+// NewPrefix configures a new Prefix instance.
+func NewPrefix() Prefix {
+	return Prefix{
+		Links:  Links{},
+		Prefix: epicv1.ServicePrefix{},
+		Groups: []GroupRef{},
+	}
+}
+
+// PoolUsage describes a ServicePrefix's address pool. The counts are
+// JSON numbers but IPv6 pools can be too big for 64 bits. If the pool
+// can't be parsed then Error says why and the rest is empty.
+type PoolUsage struct {
+	Ranges    []string    `json:"ranges,omitempty"`
+	Total     json.Number `json:"total,omitempty"`
+	Allocated json.Number `json:"allocated,omitempty"`
+	Free      json.Number `json:"free,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// GroupRef identifies an LBServiceGroup in an account.
+type GroupRef struct {
+	Account string `json:"account"`
+	Name    string `json:"name"`
+	Link    string `json:"link,omitempty"`
+}
+
+// PrefixList represents a list of ServicePrefixes on the wire.
+type PrefixList struct {
+	Links    Links    `json:"link"`
+	Prefixes []Prefix `json:"prefixes"`
+}
+
+// NewPrefixList configures a new PrefixList instance.
+func NewPrefixList() PrefixList {
+	return PrefixList{
+		Links:    Links{},
+		Prefixes: []Prefix{},
+	}
+}
+
+// Reservation is a public address that's held after the LoadBalancer
+// or GWProxy that had it was deleted, so a new one can get the same
+// address.
+type Reservation struct {
+	Links     Links             `json:"link"`
+	Name      string            `json:"name"`
+	Account   string            `json:"account"`
+	Address   string            `json:"address"`
+	Prefix    string            `json:"prefix"`
+	Group     string            `json:"group"`
+	Kind      string            `json:"kind"`
+	Object    string            `json:"object"`
+	ClientRef *epicv1.ClientRef `json:"clientRef,omitempty"`
+	Created   metav1.Time       `json:"created"`
+	Expires   metav1.Time       `json:"expires"`
+}
+
+// ReservationList represents a list of reservations on the wire.
+type ReservationList struct {
+	Links        Links         `json:"link"`
+	Reservations []Reservation `json:"reservations"`
+}
+
+// NewReservationList configures a new ReservationList instance.
+func NewReservationList() ReservationList {
+	return ReservationList{
+		Links:        Links{},
+		Reservations: []Reservation{},
+	}
+}
+
+// Lookup is the result of a search for the objects that own a public
+// address or hostname.
+type Lookup struct {
+	Links    Links         `json:"link"`
+	IP       string        `json:"ip,omitempty"`
+	Hostname string        `json:"hostname,omitempty"`
+	Matches  []LookupMatch `json:"matches"`
+}
+
+// NewLookup configures a new Lookup instance.
+func NewLookup() Lookup {
+	return Lookup{
+		Links:   Links{},
+		Matches: []LookupMatch{},
+	}
+}
+
+// LookupMatch is one object that a Lookup found. Clusters are the
+// client clusters that the object came from.
+type LookupMatch struct {
+	Kind        string            `json:"kind"`
+	Account     string            `json:"account"`
+	Name        string            `json:"name"`
+	DisplayName string            `json:"displayName,omitempty"`
+	Group       string            `json:"group,omitempty"`
+	Address     string            `json:"address,omitempty"`
+	Hostnames   []string          `json:"hostnames,omitempty"`
+	Clusters    []string          `json:"clusters,omitempty"`
+	ClientRef   *epicv1.ClientRef `json:"clientRef,omitempty"`
+	Link        string            `json:"link,omitempty"`
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Metadata fields that a RedactionPolicy can expose.
+const (
+	FieldName              = "name"
+	FieldNamespace         = "namespace"
+	FieldUID               = "uid"
+	FieldCreationTimestamp = "creationTimestamp"
+	FieldResourceVersion   = "resourceVersion"
+	FieldGeneration        = "generation"
+	FieldLabels            = "labels"
+	FieldAnnotations       = "annotations"
+)
+
+// MetadataFields are the valid values of RedactionPolicy.Fields.
+var MetadataFields = []string{
+	FieldName,
+	FieldNamespace,
+	FieldUID,
+	FieldCreationTimestamp,
+	FieldResourceVersion,
+	FieldGeneration,
+	FieldLabels,
+	FieldAnnotations,
+}
+
+// RedactionPolicy decides which Kubernetes metadata our clients see.
+// Everything else (managedFields, finalizers, owner references,
+// etc.) is always removed.
+type RedactionPolicy struct {
+	// Fields are the ObjectMeta fields to expose. See MetadataFields.
+	Fields []string
+
+	// InternalLabelDomains are label and annotation key prefix
+	// domains that are ours, not the client's. Keys in these domains
+	// or their subdomains are removed, e.g., "acnodal.io" removes
+	// "epic.acnodal.io/owning-account".
+	InternalLabelDomains []string
+}
+
+// DefaultRedactionPolicy exposes the object's name, creation time,
+// client labels, and resourceVersion (which is also its ETag).
+func DefaultRedactionPolicy() RedactionPolicy {
+	return RedactionPolicy{
+		Fields:               []string{FieldName, FieldCreationTimestamp, FieldLabels, FieldResourceVersion},
+		InternalLabelDomains: []string{"acnodal.io", "epic-gateway.org", "kubernetes.io", "k8s.io"},
+	}
+}
+
+// Validate checks that the policy makes sense.
+func (p RedactionPolicy) Validate() error {
+	for _, field := range p.Fields {
+		valid := false
+		for _, known := range MetadataFields {
+			if field == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown metadata field %q, expected one of %s", field, strings.Join(MetadataFields, ", "))
+		}
+	}
+	return nil
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultRedactionPolicy()
+)
+
+// SetRedactionPolicy sets the policy that Redact applies. It's
+// meant to be called once at startup.
+func SetRedactionPolicy(p RedactionPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+	return nil
+}
+
+// Object is a wire type that wraps a Kubernetes object.
+type Object interface {
+	objectMeta() *metav1.ObjectMeta
+}
+
+func (o *Account) objectMeta() *metav1.ObjectMeta  { return &o.Account.ObjectMeta }
+func (o *Group) objectMeta() *metav1.ObjectMeta    { return &o.Group.ObjectMeta }
+func (o *Service) objectMeta() *metav1.ObjectMeta  { return &o.Service.ObjectMeta }
+func (o *Proxy) objectMeta() *metav1.ObjectMeta    { return &o.Proxy.ObjectMeta }
+func (o *Slice) objectMeta() *metav1.ObjectMeta    { return &o.Slice.ObjectMeta }
+func (o *Endpoint) objectMeta() *metav1.ObjectMeta { return &o.Endpoint.ObjectMeta }
+func (o *Route) objectMeta() *metav1.ObjectMeta    { return &o.Route.ObjectMeta }
+func (o *Prefix) objectMeta() *metav1.ObjectMeta   { return &o.Prefix.ObjectMeta }
+
+// Redact removes the metadata that the policy doesn't expose from
+// obj, in place. It returns obj's ETag, which is based on its
+// resourceVersion whether or not the policy exposes it.
+func Redact(obj Object) (etag string) {
+	policyMu.RLock()
+	p := policy
+	policyMu.RUnlock()
+
+	meta := obj.objectMeta()
+	etag = ETag(meta.ResourceVersion)
+	*meta = p.redact(meta)
+	return etag
+}
+
+// ETag formats a resourceVersion as an HTTP entity tag.
+func ETag(resourceVersion string) string {
+	if resourceVersion == "" {
+		return ""
+	}
+	return `"` + resourceVersion + `"`
+}
+
+func (p RedactionPolicy) redact(in *metav1.ObjectMeta) metav1.ObjectMeta {
+	out := metav1.ObjectMeta{}
+	for _, field := range p.Fields {
+		switch field {
+		case FieldName:
+			out.Name = in.Name
+		case FieldNamespace:
+			out.Namespace = in.Namespace
+		case FieldUID:
+			out.UID = in.UID
+		case FieldCreationTimestamp:
+			out.CreationTimestamp = in.CreationTimestamp
+		case FieldResourceVersion:
+			out.ResourceVersion = in.ResourceVersion
+		case FieldGeneration:
+			out.Generation = in.Generation
+		case FieldLabels:
+			out.Labels = p.clientKeys(in.Labels)
+		case FieldAnnotations:
+			out.Annotations = p.clientKeys(in.Annotations)
+		}
+	}
+	return out
+}
+
+// clientKeys returns the entries of m whose keys aren't in our
+// internal domains, or nil if there aren't any.
+func (p RedactionPolicy) clientKeys(m map[string]string) map[string]string {
+	var out map[string]string
+	for key, value := range m {
+		if p.internal(key) {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[key] = value
+	}
+	return out
+}
+
+func (p RedactionPolicy) internal(key string) bool {
+	slash := strings.Index(key, "/")
+	if slash < 0 {
+		// Unprefixed keys are the client's.
+		return false
+	}
+	domain := key[:slash]
+	for _, internal := range p.InternalLabelDomains {
+		if domain == internal || strings.HasSuffix(domain, "."+internal) {
+			return true
+		}
+	}
+	return false
+}
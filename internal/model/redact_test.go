@@ -0,0 +1,155 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fullMeta returns ObjectMeta with every field that redaction cares
+// about filled in.
+func fullMeta() metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:              "web",
+		Namespace:         "epic-root",
+		UID:               "b6f1a4d2-0000-4000-8000-000000000000",
+		ResourceVersion:   "42",
+		Generation:        3,
+		CreationTimestamp: metav1.NewTime(time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)),
+		Labels: map[string]string{
+			"app":                            "web",
+			"epic.acnodal.io/owning-account": "root",
+			"app.kubernetes.io/name":         "web",
+		},
+		Annotations: map[string]string{
+			"note":                               "client's",
+			"kubectl.kubernetes.io/last-applied": "{}",
+		},
+		Finalizers:    []string{"epic.acnodal.io/controller"},
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "web-service"}},
+	}
+}
+
+func TestRedact(t *testing.T) {
+	defer SetRedactionPolicy(DefaultRedactionPolicy())
+
+	tests := []struct {
+		name   string
+		policy RedactionPolicy
+		want   metav1.ObjectMeta
+	}{
+		{
+			name:   "default",
+			policy: DefaultRedactionPolicy(),
+			want: metav1.ObjectMeta{
+				Name:              "web",
+				ResourceVersion:   "42",
+				CreationTimestamp: metav1.NewTime(time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)),
+				Labels:            map[string]string{"app": "web"},
+			},
+		},
+		{
+			name:   "every field",
+			policy: RedactionPolicy{Fields: MetadataFields, InternalLabelDomains: DefaultRedactionPolicy().InternalLabelDomains},
+			want: metav1.ObjectMeta{
+				Name:              "web",
+				Namespace:         "epic-root",
+				UID:               "b6f1a4d2-0000-4000-8000-000000000000",
+				ResourceVersion:   "42",
+				Generation:        3,
+				CreationTimestamp: metav1.NewTime(time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)),
+				Labels:            map[string]string{"app": "web"},
+				Annotations:       map[string]string{"note": "client's"},
+			},
+		},
+		{
+			name:   "no internal domains",
+			policy: RedactionPolicy{Fields: []string{FieldLabels}},
+			want:   metav1.ObjectMeta{Labels: fullMeta().Labels},
+		},
+		{
+			name:   "nothing",
+			policy: RedactionPolicy{},
+			want:   metav1.ObjectMeta{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := SetRedactionPolicy(test.policy); err != nil {
+				t.Fatal(err)
+			}
+			service := NewService()
+			service.Service.ObjectMeta = fullMeta()
+
+			etag := Redact(&service)
+			if etag != `"42"` {
+				t.Errorf("etag = %s, want \"42\" whether or not resourceVersion is exposed", etag)
+			}
+			if !reflect.DeepEqual(service.Service.ObjectMeta, test.want) {
+				t.Errorf("got %#v, want %#v", service.Service.ObjectMeta, test.want)
+			}
+		})
+	}
+}
+
+func TestRedactEveryKind(t *testing.T) {
+	for _, obj := range []Object{&Account{}, &Group{}, &Service{}, &Proxy{}, &Slice{}, &Endpoint{}, &Route{}, &Prefix{}} {
+		*obj.objectMeta() = fullMeta()
+		Redact(obj)
+		if meta := obj.objectMeta(); meta.Namespace != "" || meta.ManagedFields != nil || meta.Finalizers != nil {
+			t.Errorf("%T: metadata not redacted: %#v", obj, meta)
+		}
+	}
+}
+
+func TestInternalKey(t *testing.T) {
+	p := DefaultRedactionPolicy()
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"app", false},
+		{"example.com/tier", false},
+		{"acnodal.io/x", true},
+		{"epic.acnodal.io/owning-account", true},
+		{"notacnodal.io/x", false},
+		{"epic-gateway.org/x", true},
+		{"app.kubernetes.io/name", true},
+		{"k8s.io/x", true},
+		{"kubernetes.io.example.com/x", false},
+	}
+
+	for _, test := range tests {
+		if got := p.internal(test.key); got != test.want {
+			t.Errorf("internal(%q) = %v, want %v", test.key, got, test.want)
+		}
+	}
+}
+
+func TestSetRedactionPolicyInvalid(t *testing.T) {
+	defer SetRedactionPolicy(DefaultRedactionPolicy())
+
+	if err := SetRedactionPolicy(RedactionPolicy{Fields: []string{"managedFields"}}); err == nil {
+		t.Fatal("SetRedactionPolicy() accepted an unknown field")
+	}
+
+	// The previous policy still applies.
+	service := NewService()
+	service.Service.ObjectMeta = fullMeta()
+	Redact(&service)
+	if service.Service.Name != "web" {
+		t.Errorf("name = %q, want the default policy to keep it", service.Service.Name)
+	}
+}
+
+func TestETag(t *testing.T) {
+	if got := ETag(""); got != "" {
+		t.Errorf("ETag(\"\") = %q, want \"\"", got)
+	}
+	if got := ETag("42"); got != `"42"` {
+		t.Errorf("ETag(\"42\") = %q, want \"\\\"42\\\"\"", got)
+	}
+}
@@ -0,0 +1,201 @@
+// Package pool works with the address pools in ServicePrefixes. EPIC
+// allocates the addresses itself when LoadBalancers and GWProxies
+// are created, so this package only reads: it parses pools and
+// measures how much of each is in use.
+package pool
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+)
+
+// Range is an inclusive range of IP addresses.
+type Range struct {
+	First net.IP
+	Last  net.IP
+}
+
+// String returns r in range notation, e.g.,
+// "192.168.1.1-192.168.1.254".
+func (r Range) String() string {
+	return r.First.String() + "-" + r.Last.String()
+}
+
+// Size returns the number of addresses in r.
+func (r Range) Size() *big.Int {
+	size := new(big.Int).Sub(new(big.Int).SetBytes(r.Last), new(big.Int).SetBytes(r.First))
+	return size.Add(size, big.NewInt(1))
+}
+
+// Contains indicates whether ip is in r.
+func (r Range) Contains(ip net.IP) bool {
+	ip = normalize(ip, len(r.First))
+	if ip == nil {
+		return false
+	}
+	return bytes.Compare(ip, r.First) >= 0 && bytes.Compare(ip, r.Last) <= 0
+}
+
+// Pool is a set of address ranges.
+type Pool struct {
+	Ranges []Range
+}
+
+// Parse parses a ServicePrefix pool. It's a comma-separated list of
+// CIDRs, e.g., "192.168.1.0/24", or ranges, e.g.,
+// "192.168.1.1-192.168.1.254".
+func Parse(spec string) (Pool, error) {
+	p := Pool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r, err := parseRange(part)
+		if err != nil {
+			return Pool{}, err
+		}
+		p.Ranges = append(p.Ranges, r)
+	}
+	if len(p.Ranges) == 0 {
+		return Pool{}, fmt.Errorf("pool %q has no addresses", spec)
+	}
+	return p, nil
+}
+
+// Size returns the number of addresses in p.
+func (p Pool) Size() *big.Int {
+	size := new(big.Int)
+	for _, r := range p.Ranges {
+		size.Add(size, r.Size())
+	}
+	return size
+}
+
+// Contains indicates whether ip is in p.
+func (p Pool) Contains(ip net.IP) bool {
+	for _, r := range p.Ranges {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings returns p's ranges in range notation.
+func (p Pool) Strings() []string {
+	ranges := make([]string, 0, len(p.Ranges))
+	for _, r := range p.Ranges {
+		ranges = append(ranges, r.String())
+	}
+	return ranges
+}
+
+// Usage is how much of a Pool is allocated.
+type Usage struct {
+	Total     *big.Int
+	Allocated *big.Int
+	Free      *big.Int
+}
+
+// Usage counts the distinct addresses in addresses that are in p.
+// Addresses that aren't valid IPs or aren't in p are ignored.
+func (p Pool) Usage(addresses []string) Usage {
+	seen := map[string]struct{}{}
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil || !p.Contains(ip) {
+			continue
+		}
+		seen[ip.String()] = struct{}{}
+	}
+
+	total := p.Size()
+	allocated := big.NewInt(int64(len(seen)))
+	return Usage{
+		Total:     total,
+		Allocated: allocated,
+		Free:      new(big.Int).Sub(total, allocated),
+	}
+}
+
+func parseRange(s string) (Range, error) {
+	if strings.Contains(s, "/") {
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid pool CIDR %q: %w", s, err)
+		}
+		first := normalize(ipnet.IP, len(ipnet.Mask))
+		last := make(net.IP, len(first))
+		for i := range first {
+			last[i] = first[i] | ^ipnet.Mask[i]
+		}
+		return Range{First: first, Last: last}, nil
+	}
+
+	ends := strings.Split(s, "-")
+	if len(ends) != 2 {
+		return Range{}, fmt.Errorf("invalid pool range %q, expected a CIDR or first-last", s)
+	}
+	first, last := net.ParseIP(strings.TrimSpace(ends[0])), net.ParseIP(strings.TrimSpace(ends[1]))
+	if first == nil || last == nil {
+		return Range{}, fmt.Errorf("invalid pool range %q", s)
+	}
+	size := net.IPv6len
+	if first.To4() != nil && last.To4() != nil {
+		size = net.IPv4len
+	} else if first.To4() != nil || last.To4() != nil {
+		return Range{}, fmt.Errorf("pool range %q mixes IPv4 and IPv6", s)
+	}
+	first, last = normalize(first, size), normalize(last, size)
+	if bytes.Compare(first, last) > 0 {
+		return Range{}, fmt.Errorf("pool range %q ends before it starts", s)
+	}
+	return Range{First: first, Last: last}, nil
+}
+
+// normalize returns ip as a slice of size bytes, or nil if it's not
+// that kind of address.
+func normalize(ip net.IP, size int) net.IP {
+	if size == net.IPv4len {
+		return ip.To4()
+	}
+	if ip.To4() != nil {
+		return nil
+	}
+	return ip.To16()
+}
+
+// FirstFree returns the lowest address in p that's not in used, or
+// nil if there isn't one. The keys of used are IP strings in
+// canonical form.
+func (p Pool) FirstFree(used map[string]struct{}) net.IP {
+	for _, r := range p.Ranges {
+		ip := append(net.IP{}, r.First...)
+		for {
+			if _, taken := used[ip.String()]; !taken {
+				return ip
+			}
+			if ip.Equal(r.Last) {
+				break
+			}
+			ip = next(ip)
+		}
+	}
+	return nil
+}
+
+// next returns the address after ip.
+func next(ip net.IP) net.IP {
+	n := append(net.IP{}, ip...)
+	for i := len(n) - 1; i >= 0; i-- {
+		n[i]++
+		if n[i] != 0 {
+			break
+		}
+	}
+	return n
+}
@@ -0,0 +1,170 @@
+package pool
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+		size int64
+	}{
+		{"192.168.1.0/24", []string{"192.168.1.0-192.168.1.255"}, 256},
+		{"192.168.1.7/24", []string{"192.168.1.0-192.168.1.255"}, 256},
+		{"192.168.1.1/32", []string{"192.168.1.1-192.168.1.1"}, 1},
+		{"192.168.1.1-192.168.1.254", []string{"192.168.1.1-192.168.1.254"}, 254},
+		{"192.168.1.1 - 192.168.1.1", []string{"192.168.1.1-192.168.1.1"}, 1},
+		{"10.0.0.255-10.0.1.0", []string{"10.0.0.255-10.0.1.0"}, 2},
+		{"192.168.1.0/30, 192.168.2.10-192.168.2.11,", []string{"192.168.1.0-192.168.1.3", "192.168.2.10-192.168.2.11"}, 6},
+		{"2001:db8::/126", []string{"2001:db8::-2001:db8::3"}, 4},
+		{"2001:db8::1-2001:db8::ff", []string{"2001:db8::1-2001:db8::ff"}, 255},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			p, err := Parse(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Strings(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ranges = %v, want %v", got, test.want)
+			}
+			if got := p.Size(); got.Cmp(big.NewInt(test.size)) != 0 {
+				t.Errorf("size = %s, want %d", got, test.size)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		" , ",
+		"192.168.1.0/33",
+		"192.168.1.300/24",
+		"192.168.1.1",
+		"192.168.1.1-",
+		"192.168.1.1-192.168.1.2-192.168.1.3",
+		"192.168.1.10-192.168.1.1",
+		"192.168.1.1-2001:db8::1",
+		"not-an-address",
+	} {
+		if p, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) = %v, want error", spec, p.Strings())
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	p, err := Parse("192.168.1.0/30, 192.168.2.10-192.168.2.11, 2001:db8::/127")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.168.1.0", true},
+		{"192.168.1.3", true},
+		{"192.168.1.4", false},
+		{"192.168.0.255", false},
+		{"192.168.2.9", false},
+		{"192.168.2.10", true},
+		{"192.168.2.11", true},
+		{"192.168.2.12", false},
+		{"::ffff:192.168.1.1", true},
+		{"2001:db8::1", true},
+		{"2001:db8::2", false},
+		{"::", false},
+	}
+
+	for _, test := range tests {
+		if got := p.Contains(net.ParseIP(test.ip)); got != test.want {
+			t.Errorf("Contains(%s) = %v, want %v", test.ip, got, test.want)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	p, err := Parse("192.168.1.0/30, 192.168.2.10-192.168.2.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		addresses []string
+		allocated int64
+		free      int64
+	}{
+		{"empty", nil, 0, 6},
+		{"some", []string{"192.168.1.0", "192.168.2.11"}, 2, 4},
+		{"duplicates", []string{"192.168.1.1", "192.168.1.1", "::ffff:192.168.1.1"}, 1, 5},
+		{"outside the pool", []string{"192.168.1.4", "10.0.0.1", "2001:db8::1"}, 0, 6},
+		{"not addresses", []string{"", "pending", "192.168.1"}, 0, 6},
+		{"full", []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.2.10", "192.168.2.11"}, 6, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usage := p.Usage(test.addresses)
+			if usage.Total.Cmp(big.NewInt(6)) != 0 {
+				t.Errorf("total = %s, want 6", usage.Total)
+			}
+			if usage.Allocated.Cmp(big.NewInt(test.allocated)) != 0 {
+				t.Errorf("allocated = %s, want %d", usage.Allocated, test.allocated)
+			}
+			if usage.Free.Cmp(big.NewInt(test.free)) != 0 {
+				t.Errorf("free = %s, want %d", usage.Free, test.free)
+			}
+		})
+	}
+}
+
+func TestFirstFree(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		used []string
+		want string
+	}{
+		{"empty", "192.168.1.0/30", nil, "192.168.1.0"},
+		{"first used", "192.168.1.0/30", []string{"192.168.1.0"}, "192.168.1.1"},
+		{"only last free", "192.168.1.0/30", []string{"192.168.1.0", "192.168.1.1", "192.168.1.2"}, "192.168.1.3"},
+		{"full", "192.168.1.0/30", []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"}, ""},
+		{"next range", "192.168.1.0/31, 192.168.2.10-192.168.2.11", []string{"192.168.1.0", "192.168.1.1"}, "192.168.2.10"},
+		{"carry", "10.0.0.255-10.0.1.0", []string{"10.0.0.255"}, "10.0.1.0"},
+		{"end of address space", "255.255.255.254-255.255.255.255", []string{"255.255.255.254", "255.255.255.255"}, ""},
+		{"single address", "192.168.1.1/32", nil, "192.168.1.1"},
+		{"ipv6", "2001:db8::/127", []string{"2001:db8::"}, "2001:db8::1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := Parse(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			used := map[string]struct{}{}
+			for _, ip := range test.used {
+				used[ip] = struct{}{}
+			}
+
+			got := p.FirstFree(used)
+			if test.want == "" {
+				if got != nil {
+					t.Errorf("FirstFree() = %s, want nil", got)
+				}
+				return
+			}
+			if got.String() != test.want {
+				t.Errorf("FirstFree() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// FileSource reads the certificate and key from PEM files and reloads
+// them when they change.
+type FileSource struct {
+	certHolder
+	certFile string
+	keyFile  string
+}
+
+// NewFileSource loads the certificate and key from certFile and
+// keyFile. It returns an error if they can't be loaded, so
+// misconfiguration shows up at startup.
+func NewFileSource(certFile string, keyFile string) (*FileSource, error) {
+	fs := &FileSource{certFile: certFile, keyFile: keyFile}
+	if _, _, err := fs.reload(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Watch implements CertificateSource. We watch the files' directories
+// rather than the files themselves because the files are often
+// replaced rather than rewritten: Kubernetes updates mounted Secrets
+// by swapping a symlink, and tools like cert-manager and certbot
+// write a new file and rename it. If a reload fails (e.g., we see the
+// new cert before the new key) we keep serving the old certificate
+// and try again on the next event.
+func (fs *FileSource) Watch(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("tls").WithValues("certFile", fs.certFile, "keyFile", fs.keyFile)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	dirs := map[string]struct{}{filepath.Dir(fs.certFile): {}, filepath.Dir(fs.keyFile): {}}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("unable to watch %s: %w", dir, err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			logger.V(1).Info("certificate directory changed", "event", event.String())
+			subject, notAfter, err := fs.reload()
+			if err != nil {
+				logger.Error(err, "certificate reload failed, continuing with previous certificate")
+				continue
+			}
+			logger.Info("certificate loaded", "subject", subject, "notAfter", notAfter)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error(err, "certificate watch error")
+		}
+	}
+}
+
+// reload reads the files and, if they're valid, makes them current.
+func (fs *FileSource) reload() (string, string, error) {
+	certPEM, err := os.ReadFile(fs.certFile)
+	if err != nil {
+		return "", "", err
+	}
+	keyPEM, err := os.ReadFile(fs.keyFile)
+	if err != nil {
+		return "", "", err
+	}
+	return fs.load(certPEM, keyPEM)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SecretSource reads the certificate and key from a
+// "kubernetes.io/tls" Secret and reloads them when the Secret
+// changes. It watches only the one Secret so it doesn't need to
+// cache every Secret in the namespace.
+type SecretSource struct {
+	certHolder
+	cs        kubernetes.Interface
+	namespace string
+	name      string
+
+	// resourceVersion is the version of the Secret that we loaded
+	// last.
+	resourceVersion string
+}
+
+// NewSecretSource loads the certificate and key from the Secret
+// namespace/name. It returns an error if they can't be loaded, so
+// misconfiguration shows up at startup.
+func NewSecretSource(ctx context.Context, cs kubernetes.Interface, namespace string, name string) (*SecretSource, error) {
+	ss := &SecretSource{cs: cs, namespace: namespace, name: name}
+
+	secret, err := cs.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read TLS secret %s/%s: %w", namespace, name, err)
+	}
+	if _, _, err := ss.loadSecret(secret); err != nil {
+		return nil, err
+	}
+	ss.resourceVersion = secret.ResourceVersion
+
+	return ss, nil
+}
+
+// Watch implements CertificateSource. It uses an informer that
+// lists and watches only the one Secret, so it re-lists and
+// re-watches if the API server closes the watch or the resource
+// version expires. If an update is invalid we keep serving the
+// previous certificate.
+func (ss *SecretSource) Watch(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("tls").WithValues("secret", ss.namespace+"/"+ss.name)
+
+	selector := fields.OneTermEqualSelector("metadata.name", ss.name).String()
+	factory := informers.NewSharedInformerFactoryWithOptions(ss.cs, 0,
+		informers.WithNamespace(ss.namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = selector
+		}),
+	)
+	informer := factory.Core().V1().Secrets().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ss.reload(logger, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			ss.reload(logger, obj)
+		},
+		DeleteFunc: func(interface{}) {
+			logger.Info("TLS secret deleted, continuing with previous certificate")
+		},
+	})
+	if err := informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		logger.Info("TLS secret watch error, restarting", "error", err.Error())
+	}); err != nil {
+		return err
+	}
+
+	informer.Run(ctx.Done())
+	return nil
+}
+
+// reload loads the certificate from obj, a Secret, if it's changed
+// since we last loaded it. The informer's handlers run one at a time
+// so this doesn't need a lock.
+func (ss *SecretSource) reload(logger logr.Logger, obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok || secret.ResourceVersion == ss.resourceVersion {
+		return
+	}
+	subject, notAfter, err := ss.loadSecret(secret)
+	if err != nil {
+		logger.Error(err, "certificate reload failed, continuing with previous certificate")
+		return
+	}
+	ss.resourceVersion = secret.ResourceVersion
+	logger.Info("certificate loaded", "subject", subject, "notAfter", notAfter)
+}
+
+// loadSecret makes the Secret's certificate and key current.
+func (ss *SecretSource) loadSecret(secret *corev1.Secret) (string, string, error) {
+	certPEM, hasCert := secret.Data[corev1.TLSCertKey]
+	keyPEM, hasKey := secret.Data[corev1.TLSPrivateKeyKey]
+	if !hasCert || !hasKey {
+		return "", "", fmt.Errorf("TLS secret %s/%s must have %s and %s", ss.namespace, ss.name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+	return ss.load(certPEM, keyPEM)
+}
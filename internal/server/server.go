@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Default http.Server timeouts. Our requests are small and our
+// handlers are quick (aside from the occasional cache-lag retry) so
+// these are generous.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
+// Networks that a Server can listen on.
+const (
+	NetworkTCP  = "tcp"
+	NetworkUnix = "unix"
+)
+
+// SocketMode is the file mode of Unix domain sockets. Access is
+// limited to the service's user and group, e.g., a sidecar that
+// shares a volume and a group with us.
+const SocketMode = 0660
+
+// Server serves HTTP as a controller-runtime manager.Runnable. The
+// manager starts it after the informer caches have synced, and stops
+// it when the manager's context is canceled. In-flight requests are
+// allowed to finish for up to ShutdownTimeout. A process can run
+// several Servers, e.g., one on TCP and one on a Unix domain socket.
+type Server struct {
+	// Name identifies the server in log messages.
+	Name string
+
+	// Network is NetworkTCP or NetworkUnix.
+	Network string
+
+	// Addr is the address to listen on, e.g., ":8080" for TCP or
+	// "/run/epic/web-service.sock" for Unix.
+	Addr string
+
+	// Handler handles the requests.
+	Handler http.Handler
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// ShutdownTimeout limits how long we wait for in-flight requests
+	// to drain when we're stopped.
+	ShutdownTimeout time.Duration
+
+	// TLSConfig, if non-nil, causes the server to serve HTTPS instead
+	// of HTTP.
+	TLSConfig *tls.Config
+
+	// H2C enables HTTP/2 without TLS ("h2c"), for clients that use
+	// prior knowledge or the Upgrade header. HTTP/1 clients still
+	// work. It's ignored if TLSConfig is set since HTTPS negotiates
+	// HTTP/2 by itself.
+	H2C bool
+}
+
+// New configures a new TCP Server with the default timeouts.
+func New(addr string, handler http.Handler) *Server {
+	return &Server{
+		Name:              "web-service",
+		Network:           NetworkTCP,
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		ReadTimeout:       DefaultReadTimeout,
+		WriteTimeout:      DefaultWriteTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+		ShutdownTimeout:   DefaultShutdownTimeout,
+	}
+}
+
+// Start implements manager.Runnable. It returns an error if the
+// listener can't bind, which causes the manager (and therefore the
+// process) to exit.
+func (s *Server) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName(s.Name).WithValues("network", s.Network, "addr", s.Addr)
+
+	listener, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("%s unable to listen on %s %s: %w", s.Name, s.Network, s.Addr, err)
+	}
+
+	handler := s.Handler
+	if s.H2C && s.TLSConfig == nil {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: s.IdleTimeout})
+	}
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		ReadTimeout:       s.ReadTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+		TLSConfig:         s.TLSConfig,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.TLSConfig != nil {
+			logger.Info("listening", "protocol", "https")
+			// The certificate comes from TLSConfig.GetCertificate
+			serveErr <- srv.ServeTLS(listener, "", "")
+			return
+		}
+		protocol := "http"
+		if s.H2C {
+			protocol = "h2c"
+		}
+		logger.Info("listening", "protocol", protocol)
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// The server stopped on its own, which means something went
+		// wrong.
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down", "timeout", s.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("%s shutdown: %w", s.Name, err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	logger.Info("stopped")
+	return nil
+}
+
+// listen opens the server's listener. Unix domain sockets left
+// behind by a previous process that didn't exit cleanly are removed
+// first; the listener removes its socket when it's closed.
+func (s *Server) listen() (net.Listener, error) {
+	switch s.Network {
+	case NetworkTCP, "":
+		return net.Listen(NetworkTCP, s.Addr)
+	case NetworkUnix:
+		if err := os.Remove(s.Addr); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		listener, err := net.Listen(NetworkUnix, s.Addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(s.Addr, SocketMode); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	default:
+		return nil, fmt.Errorf("unknown network %q", s.Network)
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. The
+// web service runs on every replica, whether or not it's the leader.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
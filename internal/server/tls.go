@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// watchRestartDelay is how long CertificateWatcher waits before it
+// restarts a failed watch.
+const watchRestartDelay = 10 * time.Second
+
+// CertificateSource supplies the server's TLS certificate and keeps
+// it up to date.
+type CertificateSource interface {
+	// GetCertificate returns the current certificate. It's suitable
+	// for use as tls.Config.GetCertificate.
+	GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	// Watch reloads the certificate when it changes. It blocks until
+	// ctx is done.
+	Watch(ctx context.Context) error
+}
+
+// tlsVersions maps the names that we accept in config to the tls
+// package's constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSVersion converts a TLS version name like "1.2" to its tls
+// package constant.
+func TLSVersion(name string) (uint16, error) {
+	version, ok := tlsVersions[strings.TrimPrefix(strings.ToUpper(name), "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q, expected one of 1.0, 1.1, 1.2, 1.3", name)
+	}
+	return version, nil
+}
+
+// CipherSuites converts IANA cipher suite names like
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256" to their tls package
+// IDs. Insecure suites are accepted since the operator asked for them
+// by name. An empty list means Go's default suites.
+func CipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := []uint16{}
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			valid := []string{}
+			for _, suite := range tls.CipherSuites() {
+				valid = append(valid, suite.Name)
+			}
+			sort.Strings(valid)
+			return nil, fmt.Errorf("unknown TLS cipher suite %q, expected one of %s", name, strings.Join(valid, ", "))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// NewTLSConfig returns a TLS server config that gets its certificate
+// from source. Note that Go doesn't allow TLS 1.3 cipher suites to be
+// configured so cipherSuites applies only to earlier versions.
+func NewTLSConfig(source CertificateSource, minVersion uint16, cipherSuites []uint16) *tls.Config {
+	return &tls.Config{
+		GetCertificate: source.GetCertificate,
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+	}
+}
+
+// certHolder holds the current certificate. Sources embed it so
+// they can swap certificates while the server is handshaking.
+type certHolder struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// GetCertificate implements CertificateSource.
+func (h *certHolder) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.cert == nil {
+		return nil, fmt.Errorf("no TLS certificate loaded")
+	}
+	return h.cert, nil
+}
+
+// load parses a PEM certificate and key and, if they're valid, makes
+// them current. It returns the certificate's subject and expiration
+// for logging.
+func (h *certHolder) load(certPEM []byte, keyPEM []byte) (subject string, notAfter string, err error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return "", "", err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return "", "", err
+	}
+	cert.Leaf = leaf
+
+	h.mu.Lock()
+	h.cert = &cert
+	h.mu.Unlock()
+
+	return leaf.Subject.String(), leaf.NotAfter.String(), nil
+}
+
+// CertificateWatcher runs a CertificateSource's Watch as a
+// controller-runtime manager.Runnable, so one watch serves every
+// HTTPS Server that shares the source.
+type CertificateWatcher struct {
+	Source CertificateSource
+}
+
+// Start implements manager.Runnable. It restarts the source's watch
+// if it fails, until ctx is done.
+func (w CertificateWatcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := w.Source.Watch(ctx); err != nil {
+			// A failed watch isn't fatal: we can still serve the
+			// certificate that we have.
+			log.FromContext(ctx).Error(err, "certificate watch failed, restarting", "after", watchRestartDelay)
+		}
+	}, watchRestartDelay)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// Every replica serves, so every replica needs its certificate.
+func (w CertificateWatcher) NeedLeaderElection() bool {
+	return false
+}
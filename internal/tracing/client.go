@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"reflect"
+
+	"go.opentelemetry.io/otel/attribute"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// tracingClient wraps a client.Client and records a span for each
+// call to the Kubernetes API.
+type tracingClient struct {
+	client.Client
+}
+
+// NewClient wraps cl so each of its calls is traced.
+func NewClient(cl client.Client) client.Client {
+	return &tracingClient{Client: cl}
+}
+
+// kind returns the Go type name of obj, e.g., "GWProxy". We don't
+// use the object's TypeMeta since typed objects usually don't have
+// it filled in.
+func kind(obj interface{}) string {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+func (c *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) (err error) {
+	ctx, span := Start(ctx, "k8s.Get "+kind(obj), attribute.String("k8s.namespace", key.Namespace), attribute.String("k8s.name", key.Name))
+	defer func() { End(span, err) }()
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) (err error) {
+	ctx, span := Start(ctx, "k8s.List "+kind(list))
+	defer func() { End(span, err) }()
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) (err error) {
+	ctx, span := Start(ctx, "k8s.Create "+kind(obj), attribute.String("k8s.namespace", obj.GetNamespace()), attribute.String("k8s.name", obj.GetName()))
+	defer func() { End(span, err) }()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) (err error) {
+	ctx, span := Start(ctx, "k8s.Delete "+kind(obj), attribute.String("k8s.namespace", obj.GetNamespace()), attribute.String("k8s.name", obj.GetName()))
+	defer func() { End(span, err) }()
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) (err error) {
+	ctx, span := Start(ctx, "k8s.Update "+kind(obj), attribute.String("k8s.namespace", obj.GetNamespace()), attribute.String("k8s.name", obj.GetName()))
+	defer func() { End(span, err) }()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) (err error) {
+	ctx, span := Start(ctx, "k8s.Patch "+kind(obj), attribute.String("k8s.namespace", obj.GetNamespace()), attribute.String("k8s.name", obj.GetName()))
+	defer func() { End(span, err) }()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *tracingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) (err error) {
+	ctx, span := Start(ctx, "k8s.DeleteAllOf "+kind(obj))
+	defer func() { End(span, err) }()
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName identifies this service's tracer.
+	instrumentationName = "acnodal.io/epic/web-service"
+
+	// serviceName is the service.name resource attribute on our
+	// spans.
+	serviceName = "epic-web-service"
+)
+
+// Options configures the tracing exporters.
+type Options struct {
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector. If it's
+	// empty we fall back to the standard OTEL_EXPORTER_OTLP_ENDPOINT
+	// environment variable, and if that's empty too then we don't
+	// export using OTLP.
+	OTLPEndpoint string
+
+	// OTLPInsecure disables TLS when talking to the collector.
+	OTLPInsecure bool
+
+	// Stdout enables an exporter that writes spans to stdout, one
+	// JSON object per line. It's meant for local testing.
+	Stdout bool
+}
+
+// Setup configures the global OpenTelemetry tracer provider and W3C
+// trace context propagator. The returned function flushes and shuts
+// down the exporters and should be called before the process exits.
+// If no exporter is enabled then spans are still created (so the
+// trace context still propagates) but they're not exported.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tpOpts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+
+	if opts.OTLPEndpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		httpOpts := []otlptracehttp.Option{}
+		if opts.OTLPEndpoint != "" {
+			httpOpts = append(httpOpts, otlptracehttp.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if opts.OTLPInsecure {
+			httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, httpOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+		}
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(exporter))
+	}
+
+	if opts.Stdout {
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("creating stdout exporter: %w", err)
+		}
+		tpOpts = append(tpOpts, sdktrace.WithSyncer(exporter))
+	}
+
+	tp := sdktrace.NewTracerProvider(tpOpts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp.Shutdown, nil
+}
+
+// Start starts a span that's a child of whatever span is in ctx.
+// Callers must End() the returned span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err (if it's not nil) on span and then ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
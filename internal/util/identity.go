@@ -0,0 +1,110 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// RemoteUserHeader carries the caller's identity when the service
+	// runs behind an authenticating proxy.
+	RemoteUserHeader = "X-Remote-User"
+
+	// AnonymousUser is the identity of callers that we can't identify.
+	AnonymousUser = "anonymous"
+
+	// UnverifiedPrefix marks identities that the caller claimed but
+	// that nobody checked, e.g., basic auth user names.
+	UnverifiedPrefix = "unverified:"
+)
+
+// trustedProxies are the networks of the proxies whose X-Remote-User
+// and X-Forwarded-* headers we believe.
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the CIDRs of the proxies whose X-Remote-User
+// header CallerIdentity() believes, and whose X-Forwarded-* headers
+// AbsoluteURL() believes. If cidrs is empty then the headers are
+// ignored.
+func SetTrustedProxies(cidrs []string) error {
+	nets, err := ParseCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+	trustedProxies = nets
+	return nil
+}
+
+// ParseCIDRs parses a list of CIDRs, e.g., "10.0.0.0/8".
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
+
+// CallerIdentity returns the best identity that we have for the
+// client that sent r. In order of preference that's the subject of
+// the client's verified TLS certificate, the user that a trusted
+// authenticating proxy passed in the X-Remote-User header, or the
+// basic auth user. We don't check basic auth passwords so the basic
+// auth user is prefixed with UnverifiedPrefix.
+func CallerIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	if user := r.Header.Get(RemoteUserHeader); user != "" && fromTrustedProxy(r) {
+		return user
+	}
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		return UnverifiedPrefix + user
+	}
+	return AnonymousUser
+}
+
+// fromTrustedProxy returns true if r's source address is in one of
+// the trustedProxies networks.
+func fromTrustedProxy(r *http.Request) bool {
+	ip := net.ParseIP(SourceIP(r))
+	if ip == nil {
+		return false
+	}
+	for _, ipnet := range trustedProxies {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// SourceIP returns the IP address of the client that sent r. If the
+// request came through one or more proxies then this is the address
+// of the closest proxy, not the original client; see ForwardedFor().
+func SourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ForwardedFor returns the client addresses in r's X-Forwarded-For
+// header, if any.
+func ForwardedFor(r *http.Request) []string {
+	header := r.Header.Get("X-Forwarded-For")
+	if header == "" {
+		return nil
+	}
+	addrs := []string{}
+	for _, addr := range strings.Split(header, ",") {
+		addrs = append(addrs, strings.TrimSpace(addr))
+	}
+	return addrs
+}
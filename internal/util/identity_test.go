@@ -0,0 +1,44 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallerIdentity(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		remoteUser string
+		basicUser  string
+		want       string
+	}{
+		{"trusted proxy", "10.1.2.3:4567", "alice", "", "alice"},
+		{"untrusted proxy", "192.168.1.1:4567", "alice", "", AnonymousUser},
+		{"untrusted proxy with basic auth", "192.168.1.1:4567", "alice", "bob", "unverified:bob"},
+		{"basic auth", "10.1.2.3:4567", "", "bob", "unverified:bob"},
+		{"anonymous", "10.1.2.3:4567", "", "", AnonymousUser},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+			if test.remoteUser != "" {
+				r.Header.Set(RemoteUserHeader, test.remoteUser)
+			}
+			if test.basicUser != "" {
+				r.SetBasicAuth(test.basicUser, "password")
+			}
+			if got := CallerIdentity(r); got != test.want {
+				t.Errorf("CallerIdentity() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
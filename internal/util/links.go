@@ -0,0 +1,124 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Headers that reverse proxies use to tell us what the client's URL
+// looked like before the proxy rewrote it.
+const (
+	ForwardedProtoHeader  = "X-Forwarded-Proto"
+	ForwardedHostHeader   = "X-Forwarded-Host"
+	ForwardedPrefixHeader = "X-Forwarded-Prefix"
+)
+
+// externalURL, if non-nil, is the base of every link that we
+// generate. It overrides the request and its forwarded headers.
+var externalURL *url.URL
+
+// SetExternalURL sets the base URL of the links that we send to
+// clients, e.g., "https://gateway.example.com/epic". If raw is empty,
+// links are based on the request, and its X-Forwarded-* headers if it
+// came from a trusted proxy.
+func SetExternalURL(raw string) error {
+	if raw == "" {
+		externalURL = nil
+		return nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("external URL %q must be an absolute http or https URL", raw)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	externalURL = u
+	return nil
+}
+
+// RouteURL returns the absolute URL of the route named name, filled
+// in with the route variables in pairs. See AbsoluteURL.
+func RouteURL(r *http.Request, router *mux.Router, name string, pairs ...string) (string, error) {
+	route := router.Get(name)
+	if route == nil {
+		return "", fmt.Errorf("no route named %q", name)
+	}
+	u, err := route.URL(pairs...)
+	if err != nil {
+		return "", err
+	}
+
+	// Our routers use encoded paths so route variables (e.g., cluster
+	// names) arrive, and are passed back to us, escaped. mux puts them
+	// into the URL's Path as-is, so unescape them to avoid escaping
+	// them twice.
+	if unescaped, err := url.PathUnescape(u.Path); err == nil && unescaped != u.Path {
+		u.RawPath = u.Path
+		u.Path = unescaped
+	}
+
+	return AbsoluteURL(r, u), nil
+}
+
+// AbsoluteURL makes a router-generated URL absolute, as the client
+// sees it. If an external URL is set then it's the base. Otherwise
+// the scheme, host and path prefix come from the X-Forwarded-Proto,
+// X-Forwarded-Host and X-Forwarded-Prefix headers if a trusted proxy
+// (see SetTrustedProxies()) set them, or from the request itself if
+// not. Anybody can set those headers, so behind a proxy that isn't
+// trusted the external URL must be set.
+func AbsoluteURL(r *http.Request, u *url.URL) string {
+	abs := *u
+	if externalURL != nil {
+		abs.Scheme = externalURL.Scheme
+		abs.Host = externalURL.Host
+		abs.Path = externalURL.Path + u.Path
+		if u.RawPath != "" {
+			abs.RawPath = externalURL.EscapedPath() + u.RawPath
+		}
+		return abs.String()
+	}
+
+	abs.Scheme = "http"
+	if r.TLS != nil {
+		abs.Scheme = "https"
+	}
+	abs.Host = r.Host
+	if !fromTrustedProxy(r) {
+		return abs.String()
+	}
+
+	if proto := firstHeaderValue(r, ForwardedProtoHeader); proto == "http" || proto == "https" {
+		abs.Scheme = proto
+	}
+
+	if host := firstHeaderValue(r, ForwardedHostHeader); host != "" {
+		abs.Host = host
+	}
+
+	if prefix := strings.TrimSuffix(firstHeaderValue(r, ForwardedPrefixHeader), "/"); prefix != "" {
+		if !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+		}
+		abs.Path = prefix + u.Path
+		if u.RawPath != "" {
+			abs.RawPath = prefix + u.RawPath
+		}
+	}
+
+	return abs.String()
+}
+
+// firstHeaderValue returns the first of a header's comma-separated
+// values. Proxy chains append their own values to the X-Forwarded-*
+// headers, and the first one is the closest to the client.
+func firstHeaderValue(r *http.Request, name string) string {
+	return strings.TrimSpace(strings.SplitN(r.Header.Get(name), ",", 2)[0])
+}
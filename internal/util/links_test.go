@@ -0,0 +1,45 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAbsoluteURL(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		external   string
+		want       string
+	}{
+		{"trusted proxy", "10.1.2.3:4567", "", "https://gateway.example.com/epic/accounts/root"},
+		{"untrusted proxy", "192.168.1.1:4567", "", "http://ws.internal/accounts/root"},
+		{"external URL", "192.168.1.1:4567", "https://epic.example.com/ws", "https://epic.example.com/ws/accounts/root"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := SetExternalURL(test.external); err != nil {
+				t.Fatal(err)
+			}
+			defer SetExternalURL("")
+
+			r := httptest.NewRequest(http.MethodGet, "http://ws.internal/", nil)
+			r.RemoteAddr = test.remoteAddr
+			r.Header.Set(ForwardedProtoHeader, "https")
+			r.Header.Set(ForwardedHostHeader, "gateway.example.com, evil.example.com")
+			r.Header.Set(ForwardedPrefixHeader, "epic/")
+
+			if got := AbsoluteURL(r, &url.URL{Path: "/accounts/root"}); got != test.want {
+				t.Errorf("AbsoluteURL() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/go-logr/logr"
+	"github.com/gorilla/mux"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// RequestIDHeader is the header that carries the request's
+	// correlation ID. If the client sends a valid one we use it,
+	// otherwise we generate one. Either way we echo it back in the
+	// response.
+	RequestIDHeader = "X-Request-ID"
+)
+
+var (
+	// objectVars are the URL variables that name the objects that a
+	// request operates on. Each one that's present in the request's
+	// URL is logged with its variable name as the key, so handlers
+	// that name a new object log it with its kind's key, too.
+	objectVars = []string{"group", "service", "endpoint", "cluster", "proxy", "route", "slice"}
+
+	// requestIDPattern matches the client request IDs that we accept.
+	// It keeps them short and free of characters that could forge log
+	// fields or headers.
+	requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+)
+
+// LoggingMiddleware returns a mux middleware that attaches a
+// request-scoped logger to each request's context. The logger
+// carries the request ID, account, route name and object names so
+// every message logged while handling the request can be correlated.
+// Handlers and the db layer retrieve it using log.FromContext().
+func LoggingMiddleware(logger logr.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				id = string(uuid.NewUUID())
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			vars := mux.Vars(r)
+			l := logger.WithValues("requestID", id, "method", r.Method, "route", RouteName(r))
+			if account, ok := vars["account"]; ok {
+				l = l.WithValues("account", account)
+			}
+			for _, key := range objectVars {
+				if name, ok := vars[key]; ok {
+					l = l.WithValues(key, name)
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(log.IntoContext(r.Context(), l)))
+		})
+	}
+}
+
+// RouteName returns the name of the mux route that matched r. Some
+// routes (e.g., most of the DELETE handlers) don't have names, so in
+// that case we fall back to the route's path template which has low
+// cardinality since it doesn't contain the variable values.
+func RouteName(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unknown"
+	}
+	if name := route.GetName(); name != "" {
+		return name
+	}
+	if tmpl, err := route.GetPathTemplate(); err == nil {
+		return tmpl
+	}
+	return "unknown"
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		keep bool
+	}{
+		{"valid", "abc-123_x.y", true},
+		{"longest", strings.Repeat("a", 128), true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"space", "abc 123", false},
+		{"newline", "abc\nrequestID=forged", false},
+		{"quote", `abc"`, false},
+	}
+
+	handler := LoggingMiddleware(logr.Discard())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(RequestIDHeader, test.id)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			got := w.Header().Get(RequestIDHeader)
+			if test.keep && got != test.id {
+				t.Errorf("request ID %q replaced with %q", test.id, got)
+			}
+			if !test.keep && (got == test.id || !requestIDPattern.MatchString(got)) {
+				t.Errorf("request ID %q replaced with %q", test.id, got)
+			}
+		})
+	}
+}
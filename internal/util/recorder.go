@@ -0,0 +1,34 @@
+package util
+
+import (
+	"net/http"
+)
+
+// StatusRecorder wraps an http.ResponseWriter and remembers the
+// status code that the handler sent so middleware can report on it
+// after the handler returns.
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status int
+}
+
+// NewStatusRecorder wraps w in a StatusRecorder. The status defaults
+// to 200 because that's what net/http sends if the handler never
+// calls WriteHeader.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+// WriteHeader records the status code and passes it through.
+func (r *StatusRecorder) WriteHeader(status int) {
+	r.Status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes through to the underlying writer if it supports
+// flushing.
+func (r *StatusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -2,8 +2,9 @@ package util
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
@@ -23,7 +24,7 @@ func RespondJSON(w http.ResponseWriter, status int, payload interface{}, headers
 	w.Header().Set("Content-Type", "application/json")
 	bytes, err := json.Marshal(payload)
 	if err != nil {
-		fmt.Printf("Error marshaling response %#v\n", err)
+		log.Log.WithName("respond").Error(err, "Error marshaling response")
 		bytes = []byte{}
 	}
 	w.WriteHeader(status)
@@ -44,6 +45,16 @@ func RespondProblem(w http.ResponseWriter, code int, message string) {
 	RespondJSON(w, code, map[string]string{"error": message}, map[string]string{errorMessageHeader: message})
 }
 
+// RespondDetail sends an HTTP response like RespondProblem, with a
+// machine-readable detail code, e.g., "pool-exhausted", in the body.
+func RespondDetail(w http.ResponseWriter, code int, detail string, message string, headers map[string]string) {
+	all := map[string]string{errorMessageHeader: message}
+	for k, v := range headers {
+		all[k] = v
+	}
+	RespondJSON(w, code, map[string]string{"error": message, "detail": detail}, all)
+}
+
 // RespondError sends an HTTP 5xx "internal server error" response.
 func RespondError(w http.ResponseWriter, err error) {
 	RespondProblem(w, http.StatusInternalServerError, err.Error())
@@ -1,31 +1,48 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
 	"math/rand"
-	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	uzap "go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"acnodal.io/epic/web-service/internal/admin"
+	"acnodal.io/epic/web-service/internal/audit"
+	"acnodal.io/epic/web-service/internal/compat"
+	"acnodal.io/epic/web-service/internal/config"
 	"acnodal.io/epic/web-service/internal/controller"
+	"acnodal.io/epic/web-service/internal/db"
+	"acnodal.io/epic/web-service/internal/health"
+	"acnodal.io/epic/web-service/internal/metrics"
+	"acnodal.io/epic/web-service/internal/model"
+	"acnodal.io/epic/web-service/internal/server"
+	"acnodal.io/epic/web-service/internal/tracing"
+	"acnodal.io/epic/web-service/internal/util"
 
 	epicv1 "epic-gateway.org/resource-model/api/v1"
 	// +kubebuilder:scaffold:imports
 )
 
-const (
-	// URLRoot is the common root of this service's URLs.
-	URLRoot = "/api/epic"
-)
-
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -41,45 +58,415 @@ func init() {
 }
 
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	flag.StringVar(&metricsAddr, "metrics-addr", ":7472", "The address the metric endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
-	flag.Parse()
+	cfg, printConfig, err := loadConfig(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %s\n", err)
+		os.Exit(1)
+	}
+
+	if printConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to format config: %s\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+		os.Exit(0)
+	}
+
+	// Validate() has already checked the level. The admin API can
+	// change it at runtime.
+	level, _ := cfg.ZapLevel()
+	logLevel := uzap.NewAtomicLevelAt(level)
+	ctrl.SetLogger(zap.New(zap.UseDevMode(cfg.Logging.Development), zap.Level(&logLevel)))
+	setupLog.Info("configuration loaded", "file", configPathFromArgs(os.Args[1:], os.LookupEnv))
+
+	if err := db.SetOptions(cfg.DBOptions()); err != nil {
+		setupLog.Error(err, "invalid db retry options")
+		os.Exit(1)
+	}
+	if err := db.SetReservationOptions(cfg.ReservationOptions()); err != nil {
+		setupLog.Error(err, "invalid reservation options")
+		os.Exit(1)
+	}
+	if err := model.SetRedactionPolicy(cfg.RedactionPolicy()); err != nil {
+		setupLog.Error(err, "invalid redaction policy")
+		os.Exit(1)
+	}
+	if err := util.SetExternalURL(cfg.ExternalURL); err != nil {
+		setupLog.Error(err, "invalid external URL")
+		os.Exit(1)
+	}
+	if err := util.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		setupLog.Error(err, "invalid trusted proxies")
+		os.Exit(1)
+	}
+	db.SetAccountClusterRole(cfg.Management.AccountClusterRole)
+
+	ctx := ctrl.SetupSignalHandler()
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracingOptions())
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "problem shutting down tracing")
+		}
+	}()
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "1cb3972f.acnodal.io",
+		Scheme:                 scheme,
+		MetricsBindAddress:     cfg.MetricsAddr,
+		HealthProbeBindAddress: cfg.HealthProbeAddr,
+		Port:                   cfg.WebhookPort,
+		LeaderElection:         cfg.LeaderElection.Enabled,
+		LeaderElectionID:       cfg.LeaderElection.ID,
+		// Give the web service time to drain before the manager gives
+		// up on it.
+		GracefulShutdownTimeout: durationPtr(cfg.Timeouts.Shutdown.Duration + 5*time.Second),
+		// We read only a few namespaces, so read them from the API
+		// server instead of caching (and watching) every namespace. The
+		// only ConfigMaps we read are the address reservations and
+		// picks, and allocation needs their latest versions, so read
+		// those from the API server too.
+		ClientDisableCacheFor: []client.Object{&corev1.Namespace{}, &corev1.ConfigMap{}},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
+	cl := tracing.NewClient(db.NewConsistentClient(mgr.GetClient(), mgr.GetAPIReader(), cfg.Timeouts.ReadYourWrites.Duration))
+
+	// The pool gauges are computed on each scrape so they read from
+	// the cache.
+	if err := metrics.RegisterPoolCollector(mgr.GetClient()); err != nil {
+		setupLog.Error(err, "unable to register pool metrics")
+		os.Exit(1)
+	}
+
+	// check that the cluster's CRDs match our compiled-in types
+	if err := checkCRDs(ctx, mgr.GetConfig()); err != nil {
+		if cfg.RequireCompatibleCRDs {
+			setupLog.Error(err, "CRD compatibility check failed")
+			os.Exit(1)
+		}
+		setupLog.Error(err, "CRD compatibility check failed, continuing anyway")
+	}
+
+	// set up health checks
+	dcConfig := rest.CopyConfig(mgr.GetConfig())
+	dcConfig.Timeout = 5 * time.Second
+	dc, err := discovery.NewDiscoveryClientForConfig(dcConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to set up discovery client")
+		os.Exit(1)
+	}
+	liveChecks := health.Checks{}
+	liveChecks.Add("ping", healthz.Ping)
+	readyChecks := health.Checks{}
+	readyChecks.Add("cache-synced", health.CacheSynced(mgr.GetCache()))
+	readyChecks.Add("apiserver", health.APIServerReachable(dc))
+	readyChecks.Add("crds", health.CRDsInstalled(dc))
+	for _, check := range liveChecks {
+		if err := mgr.AddHealthzCheck(check.Name, check.Check); err != nil {
+			setupLog.Error(err, "unable to set up liveness check", "check", check.Name)
+			os.Exit(1)
+		}
+	}
+	for _, check := range readyChecks {
+		if err := mgr.AddReadyzCheck(check.Name, check.Check); err != nil {
+			setupLog.Error(err, "unable to set up readiness check", "check", check.Name)
+			os.Exit(1)
+		}
+	}
+
+	// set up auditing
+	policy := audit.DefaultPolicy()
+	if cfg.Audit.Policy != "" {
+		if policy, err = audit.LoadPolicy(cfg.Audit.Policy); err != nil {
+			setupLog.Error(err, "unable to load audit policy")
+			os.Exit(1)
+		}
+	}
+	sinks := []audit.Sink{}
+	if cfg.Audit.File != "" {
+		sink, err := audit.NewFileSink(cfg.Audit.File)
+		if err != nil {
+			setupLog.Error(err, "unable to open audit file")
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.Audit.Stdout {
+		sinks = append(sinks, audit.NewStdoutSink())
+	}
+	if cfg.Audit.Webhook != "" {
+		sinks = append(sinks, audit.NewWebhookSink(cfg.Audit.Webhook))
+	}
+	auditor := audit.NewAuditor(policy, sinks...)
+	defer auditor.Close()
 
 	// +kubebuilder:scaffold:builder
 
-	// set up web service
-	setupLog.Info("starting web service")
-	r := mux.NewRouter().UseEncodedPath()
-	controller.SetupGWProxyRoutes(r.PathPrefix(URLRoot).Subrouter(), mgr.GetClient())
-	controller.SetupGWRouteRoutes(r.PathPrefix(URLRoot).Subrouter(), mgr.GetClient())
-	controller.SetupSliceRoutes(r.PathPrefix(URLRoot).Subrouter(), mgr.GetClient())
-	controller.SetupEPICRoutes(r.PathPrefix(URLRoot).Subrouter(), mgr.GetClient())
-	controller.SetupHealthzRoutes(r.PathPrefix(URLRoot).Subrouter())
+	// set up TLS
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		source, err := certificateSource(ctx, cfg.TLS, mgr.GetConfig())
+		if err != nil {
+			setupLog.Error(err, "unable to load TLS certificate")
+			os.Exit(1)
+		}
+		if err := mgr.Add(server.CertificateWatcher{Source: source}); err != nil {
+			setupLog.Error(err, "unable to set up TLS certificate watcher")
+			os.Exit(1)
+		}
+		// Validate() has already checked the version and suites
+		minVersion, _ := server.TLSVersion(cfg.TLS.MinVersion)
+		suites, _ := server.CipherSuites(cfg.TLS.CipherSuites)
+		tlsConfig = server.NewTLSConfig(source, minVersion, suites)
+	}
+
+	// set up web service. Each listener has its own router that
+	// serves the route groups that it's configured with.
+	recorder := mgr.GetEventRecorderFor("epic-web-service")
+	adminAPI := admin.New(logLevel, mgr.GetCache())
+	routeGroups := map[string]func(*mux.Router){
+		config.RoutesAPI: func(r *mux.Router) {
+			controller.SetupGWProxyRoutes(r.PathPrefix(cfg.URLRoot).Subrouter(), cl, recorder)
+			controller.SetupGWRouteRoutes(r.PathPrefix(cfg.URLRoot).Subrouter(), cl, recorder)
+			controller.SetupSliceRoutes(r.PathPrefix(cfg.URLRoot).Subrouter(), cl, recorder)
+			controller.SetupEPICRoutes(r.PathPrefix(cfg.URLRoot).Subrouter(), cl, recorder)
+			controller.SetupReservationRoutes(r.PathPrefix(cfg.URLRoot).Subrouter(), cl, recorder)
+			controller.SetupLookupRoutes(r.PathPrefix(cfg.URLRoot).Subrouter(), cl)
+		},
+		config.RoutesHealth: func(r *mux.Router) {
+			controller.SetupHealthzRoutes(r.PathPrefix(cfg.URLRoot).Subrouter(), liveChecks, readyChecks)
+		},
+		config.RoutesAdmin: adminAPI.SetupRoutes,
+	}
+	if cfg.Management.Enabled() {
+		token, err := util.ReadToken(cfg.Management.TokenFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read management token")
+			os.Exit(1)
+		}
+		routeGroups[config.RoutesManagement] = func(r *mux.Router) {
+			mr := r.PathPrefix(cfg.URLRoot + "/admin").Subrouter()
+			mr.Use(util.BearerTokenMiddleware("epic-management", token))
+			controller.SetupAccountAdminRoutes(mr, cl, recorder, cfg.Management.DefaultGroups, cfg.Management.AccountClusterRole)
+			controller.SetupPrefixAdminRoutes(mr, cl)
+			controller.SetupLookupAdminRoutes(mr, cl)
+		}
+	}
+	for _, listener := range cfg.EffectiveListeners() {
+		r := mux.NewRouter().UseEncodedPath()
+		r.Use(metrics.NewMiddleware(mgr.GetClient()), tracing.Middleware, util.LoggingMiddleware(ctrl.Log.WithName("web-service")), auditor.Middleware)
+		for _, group := range listener.Routes {
+			routeGroups[group](r)
+		}
+		adminAPI.AddRouter(listener.Name, r)
 
-	http.Handle("/", r)
-	go http.ListenAndServe(":8080", nil)
+		ws := server.New(listener.Address, r)
+		ws.Name = listener.Name
+		ws.Network = listener.Network
+		ws.ReadHeaderTimeout = cfg.Timeouts.ReadHeader.Duration
+		ws.ReadTimeout = cfg.Timeouts.Read.Duration
+		ws.WriteTimeout = cfg.Timeouts.Write.Duration
+		ws.IdleTimeout = cfg.Timeouts.Idle.Duration
+		ws.ShutdownTimeout = cfg.Timeouts.Shutdown.Duration
+		switch listener.Protocol {
+		case config.ProtocolHTTPS:
+			ws.TLSConfig = tlsConfig
+		case config.ProtocolH2C:
+			ws.H2C = true
+		}
+		if err := mgr.Add(ws); err != nil {
+			setupLog.Error(err, "unable to set up web service", "listener", listener.Name)
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// checkCRDs compares the cluster's EPIC CRDs with our compiled-in
+// types, logs what it finds, and returns an error if they're
+// incompatible.
+func checkCRDs(ctx context.Context, cfg *rest.Config) error {
+	cs, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	report, err := compat.Check(ctx, cs)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range report.Issues {
+		setupLog.Info("CRD compatibility", "modelVersion", report.ModelVersion, "issue", issue.String())
+	}
+	if !report.Compatible() {
+		return fmt.Errorf("cluster CRDs are incompatible with resource-model %s, see log for details", report.ModelVersion)
+	}
+
+	setupLog.Info("CRDs are compatible", "modelVersion", report.ModelVersion, "warnings", len(report.Issues))
+	return nil
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
+
+// certificateSource returns the web service's TLS certificate
+// source: either a pair of files or a Secret.
+func certificateSource(ctx context.Context, tlsCfg config.TLS, restCfg *rest.Config) (server.CertificateSource, error) {
+	if tlsCfg.CertFile != "" {
+		return server.NewFileSource(tlsCfg.CertFile, tlsCfg.KeyFile)
+	}
+
+	namespace, name, err := tlsCfg.SecretKey()
+	if err != nil {
+		return nil, err
+	}
+	cs, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+	return server.NewSecretSource(ctx, cs, namespace, name)
+}
+
+// splitList splits a comma-separated flag value, ignoring empty
+// items.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadConfig builds the configuration from, in increasing order of
+// precedence: the defaults, the config file, EPIC_WS_* environment
+// variables (looked up with lookup), and the command-line flags in
+// args, which are defined on fs. We need to know the config file
+// before we define the flags so the flags' defaults reflect the file
+// and environment.
+func loadConfig(fs *flag.FlagSet, args []string, lookup func(string) (string, bool)) (cfg *config.Config, printConfig bool, err error) {
+	configPath := configPathFromArgs(args, lookup)
+	cfg = config.Default()
+	if configPath != "" {
+		if err := cfg.LoadFile(configPath); err != nil {
+			return nil, false, fmt.Errorf("unable to load config: %w", err)
+		}
+	}
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		return nil, false, fmt.Errorf("unable to apply environment: %w", err)
+	}
+
+	var retryOn, cipherSuites, trustedProxies string
+	fs.String("config", configPath, "YAML file of "+config.Kind+" settings. Can also be set with "+config.EnvPrefix+"CONFIG.")
+	fs.BoolVar(&printConfig, "print-config", false, "Print the effective configuration as YAML and exit.")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "The address the metric endpoint binds to.")
+	fs.BoolVar(&cfg.LeaderElection.Enabled, "enable-leader-election", cfg.LeaderElection.Enabled,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	fs.StringVar(&cfg.Tracing.OTLPEndpoint, "otlp-endpoint", cfg.Tracing.OTLPEndpoint,
+		"The host:port of the OTLP/HTTP trace collector. "+
+			"If empty, the OTEL_EXPORTER_OTLP_ENDPOINT environment variable is used.")
+	fs.BoolVar(&cfg.Tracing.OTLPInsecure, "otlp-insecure", cfg.Tracing.OTLPInsecure, "Connect to the OTLP trace collector without TLS.")
+	fs.BoolVar(&cfg.Tracing.Stdout, "trace-stdout", cfg.Tracing.Stdout, "Write trace spans to stdout (for local testing).")
+	fs.StringVar(&cfg.Audit.File, "audit-file", cfg.Audit.File, "Append audit events to this file as JSON lines.")
+	fs.BoolVar(&cfg.Audit.Stdout, "audit-stdout", cfg.Audit.Stdout, "Write audit events to stdout as JSON lines.")
+	fs.StringVar(&cfg.Audit.Webhook, "audit-webhook", cfg.Audit.Webhook, "POST audit events as JSON to this URL.")
+	fs.StringVar(&cfg.Audit.Policy, "audit-policy", cfg.Audit.Policy, "YAML file that sets the audit level for each resource kind.")
+	fs.StringVar(&cfg.ListenAddr, "listen-addr", cfg.ListenAddr, "The address the web service binds to.")
+	fs.StringVar(&cfg.ExternalURL, "external-url", cfg.ExternalURL,
+		"Base URL of the links in responses, e.g., https://gateway.example.com/epic-ws. "+
+			"If empty, links are based on each request, and its X-Forwarded-Proto/Host/Prefix headers if it came from a trusted proxy.")
+	fs.StringVar(&trustedProxies, "trusted-proxies", strings.Join(cfg.TrustedProxies, ","),
+		"Comma-separated CIDRs of the proxies whose X-Remote-User header identifies the caller and whose X-Forwarded-* headers are used in links.")
+	fs.StringVar(&cfg.AdminAddr, "admin-addr", cfg.AdminAddr,
+		"The address the admin API (pprof, log level, stats), and the account management API unless it's public, binds to. Keep it on loopback. If empty, the admin API isn't served on its own port.")
+	fs.StringVar(&cfg.HealthProbeAddr, "health-probe-addr", cfg.HealthProbeAddr, "The address the manager's liveness and readiness probe endpoints bind to.")
+	fs.BoolVar(&cfg.RequireCompatibleCRDs, "require-compatible-crds", cfg.RequireCompatibleCRDs,
+		"Refuse to start if the cluster's EPIC CRDs are incompatible with the compiled-in resource model.")
+	fs.DurationVar(&cfg.Timeouts.ReadYourWrites.Duration, "read-your-writes-timeout", cfg.Timeouts.ReadYourWrites.Duration,
+		"How long a read waits for the informer cache to catch up with an earlier write before reading from the API server.")
+	fs.DurationVar(&cfg.Retry.InitialInterval.Duration, "db-retry-initial-interval", cfg.Retry.InitialInterval.Duration, "Delay before the first retry of a failed Kubernetes API call.")
+	fs.Float64Var(&cfg.Retry.Multiplier, "db-retry-multiplier", cfg.Retry.Multiplier, "Factor by which the retry delay grows after each attempt.")
+	fs.DurationVar(&cfg.Retry.MaxInterval.Duration, "db-retry-max-interval", cfg.Retry.MaxInterval.Duration, "Upper limit on the delay between retries.")
+	fs.Float64Var(&cfg.Retry.Jitter, "db-retry-jitter", cfg.Retry.Jitter, "Randomize each retry delay by +/- this fraction (0.0-1.0).")
+	fs.DurationVar(&cfg.Retry.MaxElapsed.Duration, "db-retry-max-elapsed", cfg.Retry.MaxElapsed.Duration, "Give up retrying after this much time, or 0 for no limit.")
+	fs.IntVar(&cfg.Retry.MaxAttempts, "db-retry-max-attempts", cfg.Retry.MaxAttempts, "Give up retrying after this many attempts.")
+	fs.StringVar(&retryOn, "db-retry-on", strings.Join(cfg.Retry.RetryOn, ","),
+		"Comma-separated classes of error to retry: "+db.RetryConflict+", "+db.RetryThrottling+", "+db.RetryTimeout+".")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert-file", cfg.TLS.CertFile, "PEM certificate file. Enables HTTPS, and is reloaded when it changes.")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key-file", cfg.TLS.KeyFile, "PEM private key file that goes with --tls-cert-file.")
+	fs.StringVar(&cfg.TLS.Secret, "tls-secret", cfg.TLS.Secret,
+		"namespace/name of a kubernetes.io/tls Secret. Enables HTTPS, and is reloaded when it changes.")
+	fs.StringVar(&cfg.TLS.MinVersion, "tls-min-version", cfg.TLS.MinVersion, "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3.")
+	fs.StringVar(&cipherSuites, "tls-cipher-suites", strings.Join(cfg.TLS.CipherSuites, ","),
+		"Comma-separated IANA names of the TLS 1.2 and earlier cipher suites to allow. If empty, Go's defaults are used.")
+	fs.StringVar(&cfg.Management.TokenFile, "management-token-file", cfg.Management.TokenFile,
+		"File that holds the bearer token for the account management API. The API is disabled if this isn't set.")
+	fs.BoolVar(&cfg.Management.Public, "management-public", cfg.Management.Public,
+		"Serve the account management API on the web service's listener. By default it's only served on --admin-addr.")
+	fs.DurationVar(&cfg.Reservations.TTL.Duration, "reservation-ttl", cfg.Reservations.TTL.Duration,
+		"How long to hold the address of a deleted service or proxy for its replacement. 0 disables holds.")
+	fs.DurationVar(&cfg.Timeouts.Shutdown.Duration, "shutdown-timeout", cfg.Timeouts.Shutdown.Duration,
+		"How long to wait for in-flight web service requests to finish when shutting down.")
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "db-retry-on":
+			cfg.Retry.RetryOn = splitList(retryOn)
+		case "tls-cipher-suites":
+			cfg.TLS.CipherSuites = splitList(cipherSuites)
+		case "trusted-proxies":
+			cfg.TrustedProxies = splitList(trustedProxies)
+		}
+	})
+
+	return cfg, printConfig, nil
+}
+
+// configPathFromArgs finds the config file path in the command line
+// or the environment. We can't use the flag package for this because
+// the other flags' defaults depend on the config file's contents.
+func configPathFromArgs(args []string, lookup func(string) (string, bool)) string {
+	path, _ := lookup(config.EnvPrefix + "CONFIG")
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if name == "config" && i+1 < len(args) {
+			path = args[i+1]
+			i++
+		} else if strings.HasPrefix(name, "config=") {
+			path = strings.TrimPrefix(name, "config=")
+		}
+	}
+	return path
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"acnodal.io/epic/web-service/internal/config"
+)
+
+// env returns a lookup function that reads from vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `apiVersion: ` + config.APIVersion + `
+kind: ` + config.Kind + `
+listenAddr: ":1111"
+retry:
+  retryOn: [conflict]
+`
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		vars        map[string]string
+		wantListen  string
+		wantRetryOn []string
+	}{
+		{"defaults", nil, nil, ":8080", []string{"conflict", "throttling", "timeout"}},
+		{"file", []string{"--config", path}, nil, ":1111", []string{"conflict"}},
+		{"file from environment", nil, map[string]string{"EPIC_WS_CONFIG": path}, ":1111", []string{"conflict"}},
+		{"environment over file", []string{"--config", path}, map[string]string{"EPIC_WS_LISTEN_ADDR": ":2222", "EPIC_WS_RETRY_RETRY_ON": "timeout"}, ":2222", []string{"timeout"}},
+		{"flag over environment", []string{"--config", path, "--listen-addr", ":3333", "--db-retry-on", "throttling"}, map[string]string{"EPIC_WS_LISTEN_ADDR": ":2222", "EPIC_WS_RETRY_RETRY_ON": "timeout"}, ":3333", []string{"throttling"}},
+		{"flag over file", []string{"-config=" + path, "-listen-addr=:3333"}, nil, ":3333", []string{"conflict"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("web-service", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			cfg, _, err := loadConfig(fs, test.args, env(test.vars))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ListenAddr != test.wantListen {
+				t.Errorf("listenAddr = %q, want %q", cfg.ListenAddr, test.wantListen)
+			}
+			if !reflect.DeepEqual(cfg.Retry.RetryOn, test.wantRetryOn) {
+				t.Errorf("retryOn = %v, want %v", cfg.Retry.RetryOn, test.wantRetryOn)
+			}
+		})
+	}
+}
+
+func TestConfigPathFromArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		vars map[string]string
+		want string
+	}{
+		{"none", []string{"--listen-addr", ":8080"}, nil, ""},
+		{"separate value", []string{"--config", "a.yaml"}, nil, "a.yaml"},
+		{"single dash", []string{"-config", "a.yaml"}, nil, "a.yaml"},
+		{"equals", []string{"--config=a.yaml"}, nil, "a.yaml"},
+		{"environment", nil, map[string]string{"EPIC_WS_CONFIG": "env.yaml"}, "env.yaml"},
+		{"flag over environment", []string{"--config", "a.yaml"}, map[string]string{"EPIC_WS_CONFIG": "env.yaml"}, "a.yaml"},
+		{"after terminator", []string{"--", "--config", "a.yaml"}, nil, ""},
+		{"missing value", []string{"--config"}, nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := configPathFromArgs(test.args, env(test.vars)); got != test.want {
+				t.Errorf("configPathFromArgs(%v) = %q, want %q", test.args, got, test.want)
+			}
+		})
+	}
+}